@@ -0,0 +1,129 @@
+// Copyright 2017 Kumina, https://kumina.nl/
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/fuze/unbound_exporter/unboundcontrol"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/common/log"
+)
+
+// statsAccumulator turns the output of stats, which resets Unbound's
+// counters after every read, back into monotonic counters by summing the
+// deltas in the exporter. This keeps counters correct even when operators
+// reset them with unbound-control stats, as long as the exporter is the
+// only one doing so.
+type statsAccumulator struct {
+	stateFile string
+
+	mu       sync.Mutex
+	totals   map[string]float64
+	counters map[string]bool
+}
+
+func newStatsAccumulator(stateFile string) (*statsAccumulator, error) {
+	a := &statsAccumulator{
+		stateFile: stateFile,
+		totals:    map[string]float64{},
+		counters:  map[string]bool{},
+	}
+	if stateFile == "" {
+		return a, nil
+	}
+	data, err := ioutil.ReadFile(stateFile)
+	if os.IsNotExist(err) {
+		return a, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, &a.totals); err != nil {
+		return nil, err
+	}
+	return a, nil
+}
+
+// isCounter reports whether stats resets a key, which is the case for the
+// counters and the histogram, but not for the uptime.
+func (a *statsAccumulator) isCounter(name string) bool {
+	if counter, ok := a.counters[name]; ok {
+		return counter
+	}
+	counter := false
+	if histogramPattern.MatchString(name) {
+		counter = true
+	} else if name != "time.up" {
+		for _, metric := range unboundMetrics {
+			if metric.pattern.MatchString(name) {
+				counter = metric.valueType == prometheus.CounterValue
+				break
+			}
+		}
+	}
+	a.counters[name] = counter
+	return counter
+}
+
+// apply adds the counters of a stats reply to the running totals and
+// returns the statistics with the counters replaced by those totals.
+func (a *statsAccumulator) apply(stats []unboundcontrol.Stat) []unboundcontrol.Stat {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	accumulated := make([]unboundcontrol.Stat, 0, len(stats))
+	for _, stat := range stats {
+		if a.isCounter(stat.Name) {
+			a.totals[stat.Name] += stat.Value
+			stat.Value = a.totals[stat.Name]
+		}
+		accumulated = append(accumulated, stat)
+	}
+	// The deltas are already consumed, so a failure to persist them
+	// must not fail the scrape.
+	if err := a.save(); err != nil {
+		log.Errorf("Failed to save accumulated counters: %s", err)
+	}
+	return accumulated
+}
+
+// save writes the totals to the state file through a rename, so a crash
+// never leaves a truncated file behind.
+func (a *statsAccumulator) save() error {
+	if a.stateFile == "" {
+		return nil
+	}
+	data, err := json.Marshal(a.totals)
+	if err != nil {
+		return err
+	}
+	tmp, err := ioutil.TempFile(filepath.Dir(a.stateFile), filepath.Base(a.stateFile)+".tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), a.stateFile)
+}
@@ -0,0 +1,60 @@
+// Copyright 2017 Kumina, https://kumina.nl/
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"context"
+
+	"github.com/fuze/unbound_exporter/unboundcontrol"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var (
+	unboundAuthZoneSerialDesc = prometheus.NewDesc(
+		prometheus.BuildFQName("unbound", "", "auth_zone_serial"),
+		"SOA serial of the currently loaded version of an auth-zone.",
+		[]string{"zone"}, nil)
+
+	unboundAuthZoneExpiredDesc = prometheus.NewDesc(
+		prometheus.BuildFQName("unbound", "", "auth_zone_expired"),
+		"Whether an auth-zone has expired because it could not be refreshed from its primaries.",
+		[]string{"zone"}, nil)
+)
+
+func CollectAuthZonesFromClient(ctx context.Context, client *unboundcontrol.Client, ch chan<- prometheus.Metric) error {
+	zones, err := client.ListAuthZones(ctx)
+	if err != nil {
+		return err
+	}
+
+	for _, zone := range zones {
+		if zone.HasSerial {
+			ch <- prometheus.MustNewConstMetric(
+				unboundAuthZoneSerialDesc,
+				prometheus.GaugeValue,
+				float64(zone.Serial),
+				zone.Name)
+		}
+		expired := 0.0
+		if zone.Expired {
+			expired = 1.0
+		}
+		ch <- prometheus.MustNewConstMetric(
+			unboundAuthZoneExpiredDesc,
+			prometheus.GaugeValue,
+			expired,
+			zone.Name)
+	}
+	return nil
+}
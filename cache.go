@@ -0,0 +1,91 @@
+// Copyright 2017 Kumina, https://kumina.nl/
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var (
+	unboundStatsAgeDesc = prometheus.NewDesc(
+		prometheus.BuildFQName("unbound", "", "stats_age_seconds"),
+		"Age of the served statistics in seconds, due to scrape caching or background polling.",
+		nil, nil)
+)
+
+type scrapeCall struct {
+	done      chan struct{}
+	metrics   []prometheus.Metric
+	collected time.Time
+}
+
+// scrapeCache coalesces concurrent scrapes into a single collection, and
+// optionally serves the result of the last collection for a while, so
+// that several Prometheus servers do not multiply the load on Unbound's
+// control socket.
+type scrapeCache struct {
+	ttl time.Duration
+
+	mu        sync.Mutex
+	metrics   []prometheus.Metric
+	collected time.Time
+	finished  time.Time
+	inflight  *scrapeCall
+}
+
+func newScrapeCache(ttl time.Duration) *scrapeCache {
+	return &scrapeCache{ttl: ttl}
+}
+
+// get returns cached metrics if they are younger than the TTL, joins a
+// collection that is already running, or runs collect itself. collect
+// returns the time its data was obtained.
+func (c *scrapeCache) get(collect func(chan<- prometheus.Metric) time.Time) ([]prometheus.Metric, time.Time) {
+	c.mu.Lock()
+	// The TTL counts from the end of the previous collection, so slow
+	// collections do not make Unbound busier.
+	if c.metrics != nil && time.Since(c.finished) < c.ttl {
+		metrics, collected := c.metrics, c.collected
+		c.mu.Unlock()
+		return metrics, collected
+	}
+	if call := c.inflight; call != nil {
+		c.mu.Unlock()
+		<-call.done
+		return call.metrics, call.collected
+	}
+	call := &scrapeCall{done: make(chan struct{})}
+	c.inflight = call
+	c.mu.Unlock()
+
+	ch := make(chan prometheus.Metric)
+	go func() {
+		call.collected = collect(ch)
+		close(ch)
+	}()
+	for metric := range ch {
+		call.metrics = append(call.metrics, metric)
+	}
+
+	c.mu.Lock()
+	c.inflight = nil
+	c.metrics, c.collected, c.finished = call.metrics, call.collected, time.Now()
+	c.mu.Unlock()
+	close(call.done)
+
+	return call.metrics, call.collected
+}
@@ -0,0 +1,69 @@
+// Copyright 2017 Kumina, https://kumina.nl/
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"math"
+	"time"
+
+	"github.com/fuze/unbound_exporter/unboundcontrol"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var unboundClockSkewDesc = prometheus.NewDesc(
+	prometheus.BuildFQName("unbound", "", "clock_skew_seconds"),
+	"Difference between Unbound's time.now and the exporter's clock when the statistics were obtained. Positive when Unbound's clock is ahead.",
+	nil, nil)
+
+// statsTime returns Unbound's own clock as reported by time.now.
+func statsTime(stats []unboundcontrol.Stat) (time.Time, bool) {
+	for _, stat := range stats {
+		if stat.Name == "time.now" {
+			sec, frac := math.Modf(stat.Value)
+			return time.Unix(int64(sec), int64(frac*1e9)), true
+		}
+	}
+	return time.Time{}, false
+}
+
+// CollectClockSkew compares Unbound's clock with the time at which the
+// exporter obtained the statistics.
+func CollectClockSkew(stats []unboundcontrol.Stat, collected time.Time, ch chan<- prometheus.Metric) {
+	now, ok := statsTime(stats)
+	if !ok {
+		return
+	}
+	ch <- prometheus.MustNewConstMetric(
+		unboundClockSkewDesc,
+		prometheus.GaugeValue,
+		now.Sub(collected).Seconds())
+}
+
+// withTimestamp returns a channel that stamps every metric sent to it with
+// ts before passing it on to ch. The returned function must be called once
+// all metrics have been sent.
+func withTimestamp(ch chan<- prometheus.Metric, ts time.Time) (chan<- prometheus.Metric, func()) {
+	stamped := make(chan prometheus.Metric)
+	done := make(chan struct{})
+	go func() {
+		for metric := range stamped {
+			ch <- prometheus.NewMetricWithTimestamp(ts, metric)
+		}
+		close(done)
+	}()
+	return stamped, func() {
+		close(stamped)
+		<-done
+	}
+}
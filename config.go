@@ -0,0 +1,156 @@
+// Copyright 2017 Kumina, https://kumina.nl/
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"context"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/fuze/unbound_exporter/unboundcontrol"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/common/log"
+)
+
+var (
+	unboundConfigOptions = []*unboundConfigOption{
+		newUnboundConfigOption(
+			"ip_ratelimit",
+			"Configured ratelimit for queries per second per client IP address. 0 means disabled.",
+			"ip-ratelimit"),
+		newUnboundConfigOption(
+			"msg_cache_size_bytes",
+			"Configured size of the message cache in bytes.",
+			"msg-cache-size"),
+		newUnboundConfigOption(
+			"num_queries_per_thread",
+			"Configured number of queries that every thread will service simultaneously.",
+			"num-queries-per-thread"),
+		newUnboundConfigOption(
+			"num_threads",
+			"Configured number of threads.",
+			"num-threads"),
+		newUnboundConfigOption(
+			"outgoing_range",
+			"Configured number of ports to open per thread.",
+			"outgoing-range"),
+		newUnboundConfigOption(
+			"prefetch",
+			"Whether message cache elements are prefetched before they expire.",
+			"prefetch"),
+		newUnboundConfigOption(
+			"ratelimit",
+			"Configured ratelimit for queries per second towards a zone. 0 means disabled.",
+			"ratelimit"),
+		newUnboundConfigOption(
+			"rrset_cache_size_bytes",
+			"Configured size of the RRset cache in bytes.",
+			"rrset-cache-size"),
+		newUnboundConfigOption(
+			"serve_expired",
+			"Whether expired records are served from the cache.",
+			"serve-expired"),
+	}
+)
+
+type unboundConfigOption struct {
+	desc   *prometheus.Desc
+	option string
+}
+
+func newUnboundConfigOption(name string, description string, option string) *unboundConfigOption {
+	return &unboundConfigOption{
+		desc: prometheus.NewDesc(
+			prometheus.BuildFQName("unbound", "config", name),
+			description,
+			nil,
+			nil),
+		option: option,
+	}
+}
+
+// parseOptionValue converts the output of get_option to a sample value.
+// Booleans are reported as yes or no.
+func parseOptionValue(value string) (float64, error) {
+	switch value {
+	case "yes":
+		return 1.0, nil
+	case "no":
+		return 0.0, nil
+	}
+	return strconv.ParseFloat(value, 64)
+}
+
+func configMetrics(ctx context.Context, client *unboundcontrol.Client) ([]prometheus.Metric, error) {
+	metrics := []prometheus.Metric{}
+	for _, option := range unboundConfigOptions {
+		values, err := client.GetOption(ctx, option.option)
+		if err != nil {
+			// Older Unbound releases may not know every option.
+			if _, ok := err.(*unboundcontrol.CommandError); ok {
+				log.Debugf("Failed to get option %s: %s", option.option, err)
+				continue
+			}
+			return nil, err
+		}
+		if len(values) != 1 {
+			log.Debugf("Option %s has %d values, skipping", option.option, len(values))
+			continue
+		}
+		value, err := parseOptionValue(values[0])
+		if err != nil {
+			log.Errorf("Failed to parse option %s: %s", option.option, err)
+			continue
+		}
+		metrics = append(metrics, prometheus.MustNewConstMetric(
+			option.desc,
+			prometheus.GaugeValue,
+			value))
+	}
+	return metrics, nil
+}
+
+// configCache keeps the configuration metrics between scrapes. Fetching
+// them takes a control connection per option, while the configuration
+// only changes when Unbound is reloaded.
+type configCache struct {
+	interval time.Duration
+
+	mu      sync.Mutex
+	metrics []prometheus.Metric
+	fetched time.Time
+}
+
+func newConfigCache(interval time.Duration) *configCache {
+	return &configCache{interval: interval}
+}
+
+// collect sends the cached configuration metrics, querying Unbound again
+// once they are older than the refresh interval.
+func (c *configCache) collect(ctx context.Context, client *unboundcontrol.Client, ch chan<- prometheus.Metric) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.metrics == nil || time.Since(c.fetched) >= c.interval {
+		metrics, err := configMetrics(ctx, client)
+		if err != nil {
+			return err
+		}
+		c.metrics, c.fetched = metrics, time.Now()
+	}
+	for _, metric := range c.metrics {
+		ch <- metric
+	}
+	return nil
+}
@@ -0,0 +1,51 @@
+// Copyright 2017 Kumina, https://kumina.nl/
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"sync"
+)
+
+// counterDeltas remembers a set of counters between scrapes, for metrics
+// computed over the interval since the previous scrape rather than since
+// server start.
+type counterDeltas struct {
+	mu       sync.Mutex
+	previous []float64
+}
+
+// update stores current and returns how much each counter grew since the
+// previous call. There is nothing to compare against on the first call,
+// or when the set of counters changed, in which case it returns false.
+func (d *counterDeltas) update(current []float64) ([]float64, bool) {
+	d.mu.Lock()
+	previous := d.previous
+	d.previous = current
+	d.mu.Unlock()
+
+	if previous == nil || len(previous) != len(current) {
+		return nil, false
+	}
+	deltas := make([]float64, len(current))
+	for i := range current {
+		// After a restart or stats reset the counters started from
+		// zero.
+		if current[i] < previous[i] {
+			copy(deltas, current)
+			return deltas, true
+		}
+		deltas[i] = current[i] - previous[i]
+	}
+	return deltas, true
+}
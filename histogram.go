@@ -0,0 +1,261 @@
+// Copyright 2017 Kumina, https://kumina.nl/
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"sort"
+	"strconv"
+
+	"github.com/fuze/unbound_exporter/unboundcontrol"
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+)
+
+var (
+	unboundThreadHistogram = prometheus.NewDesc(
+		prometheus.BuildFQName("unbound", "", "response_time_seconds"),
+		"Query response time in seconds.",
+		[]string{"thread"}, nil)
+
+	histogramPattern    = regexp.MustCompile("^(?:thread(\\d+)\\.)?histogram\\.(\\d+\\.\\d+)\\.to\\.(\\d+\\.\\d+)$")
+	recursionAvgPattern = regexp.MustCompile("^(?:thread(\\d+)|(total))\\.recursion\\.time\\.avg$")
+)
+
+// Strategies for reconstructing the histogram sum, which Unbound does not
+// report.
+const (
+	// histogramSumAvg multiplies recursion.time.avg by the sample count.
+	// The average only covers recursive replies while the buckets cover
+	// all replies, so this overestimates the sum.
+	histogramSumAvg = "avg"
+	// histogramSumMidpoint assumes every sample lies in the middle of
+	// its bucket.
+	histogramSumMidpoint = "midpoint"
+	// histogramSumNone exports NaN, so that averages computed from the
+	// sum are visibly unavailable instead of wrong.
+	histogramSumNone = "none"
+)
+
+// Unbound's buckets are powers of two, in microseconds below one second
+// and in seconds above. They are mapped onto native histogram schema 0,
+// whose buckets are (2^(i-1), 2^i] seconds, by rounding the logarithm of
+// the upper bound. Above one second this is exact, below every Unbound
+// bucket overlaps its native bucket for 93%. Unbound's first bucket,
+// starting at 0, becomes the zero bucket.
+const (
+	nativeHistogramSchema        = 0
+	nativeHistogramZeroThreshold = 1.0 / (1 << 20)
+)
+
+func validateHistogramSum(strategy string) error {
+	switch strategy {
+	case histogramSumAvg, histogramSumMidpoint, histogramSumNone:
+		return nil
+	}
+	return fmt.Errorf("Unknown histogram sum strategy %q", strategy)
+}
+
+// responseTimeHistogram holds the histogram.* keys of a single thread, or
+// of all threads for the global histogram.
+type responseTimeHistogram struct {
+	count uint64
+	// avg is the recursion.time.avg of the same thread.
+	avg           float64
+	midpointSum   float64
+	buckets       map[float64]uint64
+	zeroCount     uint64
+	nativeBuckets map[int]int64
+}
+
+func newResponseTimeHistogram() *responseTimeHistogram {
+	return &responseTimeHistogram{
+		buckets:       make(map[float64]uint64),
+		nativeBuckets: make(map[int]int64),
+	}
+}
+
+func (h *responseTimeHistogram) observe(start float64, end float64, value uint64) {
+	h.buckets[end] = value
+	h.count += value
+	h.midpointSum += float64(value) * (start + end) / 2
+
+	if start == 0 {
+		h.zeroCount += value
+	} else {
+		h.nativeBuckets[int(math.Round(math.Log2(end)))] += int64(value)
+	}
+}
+
+// parseResponseTimeHistograms collects the histogram.* keys by thread
+// number, with "total" holding the global histogram.
+func parseResponseTimeHistograms(stats []unboundcontrol.Stat) (map[string]*responseTimeHistogram, error) {
+	histograms := map[string]*responseTimeHistogram{
+		"total": newResponseTimeHistogram(),
+	}
+	histogramFor := func(thread string) *responseTimeHistogram {
+		if _, ok := histograms[thread]; !ok {
+			histograms[thread] = newResponseTimeHistogram()
+		}
+		return histograms[thread]
+	}
+
+	for _, stat := range stats {
+		if matches := histogramPattern.FindStringSubmatch(stat.Name); matches != nil {
+			start, err := strconv.ParseFloat(matches[2], 64)
+			if err != nil {
+				return nil, err
+			}
+			end, err := strconv.ParseFloat(matches[3], 64)
+			if err != nil {
+				return nil, err
+			}
+			thread := matches[1]
+			if thread == "" {
+				thread = "total"
+			}
+			histogramFor(thread).observe(start, end, uint64(stat.Value))
+		} else if matches := recursionAvgPattern.FindStringSubmatch(stat.Name); matches != nil {
+			// Only one of the thread number and "total" is set.
+			histogramFor(matches[1] + matches[2]).avg = stat.Value
+		}
+	}
+	return histograms, nil
+}
+
+// cumulativeBuckets converts Unbound's per-interval counts to cumulative
+// Prometheus buckets.
+func (h *responseTimeHistogram) cumulativeBuckets() map[float64]uint64 {
+	keys := []float64{}
+	for k := range h.buckets {
+		keys = append(keys, k)
+	}
+	sort.Float64s(keys)
+	buckets := make(map[float64]uint64, len(keys))
+	prev := uint64(0)
+	for _, k := range keys {
+		buckets[k] = h.buckets[k] + prev
+		prev = buckets[k]
+	}
+	return buckets
+}
+
+// sum reconstructs the sum of all samples. With the avg strategy,
+// hopefully this does not break monotonicity.
+func (h *responseTimeHistogram) sum(strategy string) float64 {
+	switch strategy {
+	case histogramSumMidpoint:
+		return h.midpointSum
+	case histogramSumNone:
+		return math.NaN()
+	}
+	return h.avg * float64(h.count)
+}
+
+func (h *responseTimeHistogram) collect(desc *prometheus.Desc, opts unboundOpts, ch chan<- prometheus.Metric, labelValues ...string) {
+	// Unbound only reports histogram.* keys with extended statistics
+	// enabled.
+	if len(h.buckets) == 0 {
+		return
+	}
+	metric := prometheus.MustNewConstHistogram(
+		desc,
+		h.count,
+		h.sum(opts.histogramSum),
+		h.cumulativeBuckets(),
+		labelValues...)
+	if opts.histogramNative {
+		metric = h.withNativeBuckets(metric)
+	}
+	ch <- metric
+}
+
+// nativeHistogram adds native histogram buckets to a classic histogram,
+// so that both are exposed. Native buckets are only sent to scrapers that
+// negotiate the protobuf format.
+type nativeHistogram struct {
+	prometheus.Metric
+	zeroCount uint64
+	spans     []*dto.BucketSpan
+	deltas    []int64
+}
+
+func (h *responseTimeHistogram) withNativeBuckets(metric prometheus.Metric) prometheus.Metric {
+	indices := []int{}
+	for i, count := range h.nativeBuckets {
+		if count > 0 {
+			indices = append(indices, i)
+		}
+	}
+	sort.Ints(indices)
+
+	// Encode populated buckets as spans of consecutive indices, with
+	// each count stored as the delta to the previous bucket.
+	native := &nativeHistogram{Metric: metric, zeroCount: h.zeroCount}
+	prevIndex, prevCount := 0, int64(0)
+	for n, i := range indices {
+		if n == 0 || i != prevIndex+1 {
+			offset := int32(i)
+			if n > 0 {
+				offset = int32(i - prevIndex - 1)
+			}
+			length := uint32(0)
+			native.spans = append(native.spans, &dto.BucketSpan{Offset: &offset, Length: &length})
+		}
+		*native.spans[len(native.spans)-1].Length++
+		native.deltas = append(native.deltas, h.nativeBuckets[i]-prevCount)
+		prevIndex, prevCount = i, h.nativeBuckets[i]
+	}
+	return native
+}
+
+func (m *nativeHistogram) Write(out *dto.Metric) error {
+	if err := m.Metric.Write(out); err != nil {
+		return err
+	}
+	schema := int32(nativeHistogramSchema)
+	zeroThreshold := nativeHistogramZeroThreshold
+	zeroCount := m.zeroCount
+	out.Histogram.Schema = &schema
+	out.Histogram.ZeroThreshold = &zeroThreshold
+	out.Histogram.ZeroCount = &zeroCount
+	out.Histogram.PositiveSpan = m.spans
+	out.Histogram.PositiveDelta = m.deltas
+	return nil
+}
+
+// collectResponseTimeHistograms emits the histograms keyed by thread
+// number, with "total" holding the global histogram. Unless per-thread
+// histograms are requested, only the global one is exported, without a
+// thread label.
+func collectResponseTimeHistograms(histograms map[string]*responseTimeHistogram, opts unboundOpts, ch chan<- prometheus.Metric) {
+	if !opts.histogramPerThread {
+		histograms["total"].collect(unboundHistogram, opts, ch)
+		return
+	}
+	perThread := false
+	for thread, histogram := range histograms {
+		if thread != "total" && len(histogram.buckets) > 0 {
+			perThread = true
+			histogram.collect(unboundThreadHistogram, opts, ch, thread)
+		}
+	}
+	// The global histogram duplicates the thread histograms, unless
+	// Unbound did not provide any.
+	if !perThread || opts.totals {
+		histograms["total"].collect(unboundThreadHistogram, opts, ch, "total")
+	}
+}
@@ -0,0 +1,173 @@
+// Copyright 2017 Kumina, https://kumina.nl/
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"math"
+	"reflect"
+	"testing"
+
+	"github.com/fuze/unbound_exporter/unboundcontrol"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// histogramStats returns the histogram.* keys of a small global
+// histogram, deliberately out of order.
+func histogramStats() []unboundcontrol.Stat {
+	return []unboundcontrol.Stat{
+		{Name: "histogram.000000.131072.to.000000.262144", Value: 2},
+		{Name: "histogram.000000.000000.to.000000.000001", Value: 1},
+		{Name: "histogram.000001.000000.to.000002.000000", Value: 1},
+		{Name: "histogram.000000.016384.to.000000.032768", Value: 4},
+		{Name: "total.recursion.time.avg", Value: 0.1},
+	}
+}
+
+func TestCumulativeBuckets(t *testing.T) {
+	histograms, err := parseResponseTimeHistograms(histogramStats())
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := histograms["total"].cumulativeBuckets()
+	want := map[float64]uint64{
+		0.000001: 1,
+		0.032768: 5,
+		0.262144: 7,
+		2:        8,
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Got buckets %v, want %v", got, want)
+	}
+	if count := histograms["total"].count; count != 8 {
+		t.Errorf("Got count %d, want 8", count)
+	}
+}
+
+func TestHistogramSum(t *testing.T) {
+	histograms, err := parseResponseTimeHistograms(histogramStats())
+	if err != nil {
+		t.Fatal(err)
+	}
+	histogram := histograms["total"]
+	for _, test := range []struct {
+		strategy string
+		want     float64
+	}{
+		{strategy: histogramSumAvg, want: 0.8},
+		{strategy: histogramSumMidpoint, want: 0.0000005 + 4*0.024576 + 2*0.196608 + 1.5},
+		{strategy: histogramSumNone, want: math.NaN()},
+	} {
+		t.Run(test.strategy, func(t *testing.T) {
+			got := histogram.sum(test.strategy)
+			if math.IsNaN(test.want) {
+				if !math.IsNaN(got) {
+					t.Errorf("Got sum %v, want NaN", got)
+				}
+			} else if math.Abs(got-test.want) > 1e-9 {
+				t.Errorf("Got sum %v, want %v", got, test.want)
+			}
+		})
+	}
+}
+
+func TestCollectFromStatsWithoutHistogram(t *testing.T) {
+	stats := []unboundcontrol.Stat{
+		{Name: "thread0.num.queries", Value: 10},
+		{Name: "thread0.recursion.time.avg", Value: 0.1},
+		{Name: "total.recursion.time.avg", Value: 0.1},
+	}
+	for _, perThread := range []bool{false, true} {
+		opts := unboundOpts{histogramPerThread: perThread, histogramSum: histogramSumAvg}
+		metrics := collectMetrics(t, func(ch chan<- prometheus.Metric) error {
+			return CollectFromStats(stats, opts, ch)
+		})
+		if got := len(metrics[unboundHistogram]) + len(metrics[unboundThreadHistogram]); got != 0 {
+			t.Errorf("Per thread %v: got %d histograms without histogram.* keys, want none", perThread, got)
+		}
+	}
+}
+
+func TestCollectResponseTimeHistograms(t *testing.T) {
+	global := histogramStats()
+	perThread := append(histogramStats(),
+		unboundcontrol.Stat{Name: "thread0.histogram.000000.016384.to.000000.032768", Value: 3},
+		unboundcontrol.Stat{Name: "thread1.histogram.000000.016384.to.000000.032768", Value: 1},
+		unboundcontrol.Stat{Name: "thread1.histogram.000000.131072.to.000000.262144", Value: 2},
+	)
+
+	for _, test := range []struct {
+		name       string
+		stats      []unboundcontrol.Stat
+		opts       unboundOpts
+		wantGlobal uint64
+		// wantThreads holds the sample count by thread label.
+		wantThreads map[string]uint64
+	}{
+		{
+			name:       "global",
+			stats:      perThread,
+			opts:       unboundOpts{},
+			wantGlobal: 8,
+		},
+		{
+			name:        "per thread",
+			stats:       perThread,
+			opts:        unboundOpts{histogramPerThread: true},
+			wantThreads: map[string]uint64{"0": 3, "1": 3},
+		},
+		{
+			name:        "per thread with totals",
+			stats:       perThread,
+			opts:        unboundOpts{histogramPerThread: true, totals: true},
+			wantThreads: map[string]uint64{"0": 3, "1": 3, "total": 8},
+		},
+		{
+			name:        "per thread without thread histograms",
+			stats:       global,
+			opts:        unboundOpts{histogramPerThread: true},
+			wantThreads: map[string]uint64{"total": 8},
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			histograms, err := parseResponseTimeHistograms(test.stats)
+			if err != nil {
+				t.Fatal(err)
+			}
+			test.opts.histogramSum = histogramSumAvg
+			metrics := collectMetrics(t, func(ch chan<- prometheus.Metric) error {
+				collectResponseTimeHistograms(histograms, test.opts, ch)
+				return nil
+			})
+
+			if test.wantGlobal > 0 {
+				if got := metrics[unboundHistogram]; len(got) != 1 || got[0].GetHistogram().GetSampleCount() != test.wantGlobal {
+					t.Errorf("Got global histograms %v, want one with %d samples", got, test.wantGlobal)
+				}
+			} else if got := len(metrics[unboundHistogram]); got != 0 {
+				t.Errorf("Got %d global histograms, want none", got)
+			}
+
+			got := map[string]uint64{}
+			for _, metric := range metrics[unboundThreadHistogram] {
+				got[metricLabels(metric)["thread"]] = metric.GetHistogram().GetSampleCount()
+			}
+			if test.wantThreads == nil {
+				test.wantThreads = map[string]uint64{}
+			}
+			if !reflect.DeepEqual(got, test.wantThreads) {
+				t.Errorf("Got thread histograms %v, want %v", got, test.wantThreads)
+			}
+		})
+	}
+}
@@ -0,0 +1,110 @@
+// Copyright 2017 Kumina, https://kumina.nl/
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"context"
+	"sort"
+
+	"github.com/fuze/unbound_exporter/unboundcontrol"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var (
+	unboundUpstreamRTTDesc = prometheus.NewDesc(
+		prometheus.BuildFQName("unbound", "", "upstream_rtt_seconds"),
+		"Smoothed round trip time to an upstream server, from the infrastructure cache.",
+		[]string{"ip", "zone"}, nil)
+
+	unboundUpstreamTimeoutBackoffDesc = prometheus.NewDesc(
+		prometheus.BuildFQName("unbound", "", "upstream_timeout_backoff_seconds"),
+		"Retransmission timeout for an upstream server, including exponential backoff after timeouts.",
+		[]string{"ip", "zone"}, nil)
+
+	unboundUpstreamLameDesc = prometheus.NewDesc(
+		prometheus.BuildFQName("unbound", "", "upstream_lame"),
+		"Whether an upstream server is marked lame for a zone.",
+		[]string{"ip", "zone"}, nil)
+)
+
+func CollectInfraFromClient(ctx context.Context, client *unboundcontrol.Client, limit int, ch chan<- prometheus.Metric) error {
+	entries, err := client.DumpInfra(ctx)
+	if err != nil {
+		return err
+	}
+	collectInfraEntries(entries, limit, ch)
+	return nil
+}
+
+func collectInfraEntries(entries []unboundcontrol.InfraEntry, limit int, ch chan<- prometheus.Metric) {
+	// Expired entries only carry a timeout and are about to be
+	// replaced, so they are not worth a series.
+	live := []unboundcontrol.InfraEntry{}
+	for _, entry := range entries {
+		if !entry.Expired {
+			live = append(live, entry)
+		}
+	}
+
+	// Keep the servers with the highest timeouts, as those are the
+	// ones worth looking at.
+	sort.Slice(live, func(i, j int) bool {
+		if live[i].RTO != live[j].RTO {
+			return live[i].RTO > live[j].RTO
+		}
+		if live[i].IP != live[j].IP {
+			return live[i].IP < live[j].IP
+		}
+		return live[i].Zone < live[j].Zone
+	})
+
+	// The cache is keyed by address, port and zone, but dump_infra does
+	// not print the port. Servers sharing an address for a zone are
+	// merged into the one with the highest timeout, which is lame if any
+	// of them is.
+	unique := []unboundcontrol.InfraEntry{}
+	lame := map[string]bool{}
+	for _, entry := range live {
+		key := entry.IP + " " + entry.Zone
+		if _, ok := lame[key]; !ok {
+			unique = append(unique, entry)
+		}
+		lame[key] = lame[key] || entry.Lame()
+	}
+	if limit > 0 && len(unique) > limit {
+		unique = unique[:limit]
+	}
+
+	for _, entry := range unique {
+		ch <- prometheus.MustNewConstMetric(
+			unboundUpstreamRTTDesc,
+			prometheus.GaugeValue,
+			entry.Ping.Seconds(),
+			entry.IP, entry.Zone)
+		ch <- prometheus.MustNewConstMetric(
+			unboundUpstreamTimeoutBackoffDesc,
+			prometheus.GaugeValue,
+			entry.RTO.Seconds(),
+			entry.IP, entry.Zone)
+		isLame := 0.0
+		if lame[entry.IP+" "+entry.Zone] {
+			isLame = 1.0
+		}
+		ch <- prometheus.MustNewConstMetric(
+			unboundUpstreamLameDesc,
+			prometheus.GaugeValue,
+			isLame,
+			entry.IP, entry.Zone)
+	}
+}
@@ -0,0 +1,77 @@
+// Copyright 2017 Kumina, https://kumina.nl/
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/fuze/unbound_exporter/unboundcontrol"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+func TestCollectInfraEntries(t *testing.T) {
+	entries := []unboundcontrol.InfraEntry{
+		// Two forwarders on 127.0.0.1, on ports 5053 and 5054.
+		{IP: "127.0.0.1", Zone: ".", Ping: 5 * time.Millisecond, RTO: 50 * time.Millisecond},
+		{IP: "127.0.0.1", Zone: ".", Ping: 9 * time.Millisecond, RTO: 900 * time.Millisecond, LameRec: true},
+		{IP: "192.0.2.1", Zone: "example.com.", Ping: 20 * time.Millisecond, RTO: 200 * time.Millisecond},
+		{IP: "192.0.2.2", Zone: "example.com.", Ping: 10 * time.Millisecond, RTO: 100 * time.Millisecond},
+		{IP: "192.0.2.3", Zone: "example.com.", Expired: true, RTO: 120 * time.Second},
+	}
+
+	for _, test := range []struct {
+		name  string
+		limit int
+		want  map[string]float64
+	}{
+		{
+			name: "all",
+			want: map[string]float64{"127.0.0.1": 0.9, "192.0.2.1": 0.2, "192.0.2.2": 0.1},
+		},
+		{
+			name:  "limit",
+			limit: 2,
+			want:  map[string]float64{"127.0.0.1": 0.9, "192.0.2.1": 0.2},
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			metrics := collectMetrics(t, func(ch chan<- prometheus.Metric) error {
+				collectInfraEntries(entries, test.limit, ch)
+				return nil
+			})
+			for _, desc := range []*prometheus.Desc{unboundUpstreamRTTDesc, unboundUpstreamTimeoutBackoffDesc, unboundUpstreamLameDesc} {
+				if got := len(metrics[desc]); got != len(test.want) {
+					t.Errorf("%s: got %d series, want %d", desc, got, len(test.want))
+				}
+			}
+			for ip, rto := range test.want {
+				zone := "example.com."
+				if ip == "127.0.0.1" {
+					zone = "."
+				}
+				labels := map[string]string{"ip": ip, "zone": zone}
+				metric := findMetric(metrics[unboundUpstreamTimeoutBackoffDesc], labels)
+				if metric == nil {
+					t.Errorf("No timeout backoff for %v", labels)
+				} else if got := metricValue(metric); got != rto {
+					t.Errorf("Timeout backoff for %v: got %v, want %v", labels, got, rto)
+				}
+			}
+			if metric := findMetric(metrics[unboundUpstreamLameDesc], map[string]string{"ip": "127.0.0.1", "zone": "."}); metric == nil || metricValue(metric) != 1 {
+				t.Errorf("127.0.0.1 should be lame, got %v", metric)
+			}
+		})
+	}
+}
@@ -0,0 +1,61 @@
+// Copyright 2017 Kumina, https://kumina.nl/
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"context"
+
+	"github.com/fuze/unbound_exporter/unboundcontrol"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var (
+	unboundLocalZonesDesc = prometheus.NewDesc(
+		prometheus.BuildFQName("unbound", "", "local_zones"),
+		"Number of local zones, by local-zone type.",
+		[]string{"type"}, nil)
+
+	unboundLocalDataRecordsDesc = prometheus.NewDesc(
+		prometheus.BuildFQName("unbound", "", "local_data_records"),
+		"Number of local-data records.",
+		nil, nil)
+)
+
+func CollectLocalZonesFromClient(ctx context.Context, client *unboundcontrol.Client, ch chan<- prometheus.Metric) error {
+	zones, err := client.ListLocalZones(ctx)
+	if err != nil {
+		return err
+	}
+	records, err := client.ListLocalData(ctx)
+	if err != nil {
+		return err
+	}
+
+	counts := map[string]int{}
+	for _, zone := range zones {
+		counts[zone.Type]++
+	}
+	for zoneType, count := range counts {
+		ch <- prometheus.MustNewConstMetric(
+			unboundLocalZonesDesc,
+			prometheus.GaugeValue,
+			float64(count),
+			zoneType)
+	}
+	ch <- prometheus.MustNewConstMetric(
+		unboundLocalDataRecordsDesc,
+		prometheus.GaugeValue,
+		float64(len(records)))
+	return nil
+}
@@ -0,0 +1,200 @@
+// Copyright 2017 Kumina, https://kumina.nl/
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/fuze/unbound_exporter/unboundcontrol"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/common/log"
+)
+
+// windowedGauge exports the extremes of a gauge from unboundMetrics over
+// the poll window.
+type windowedGauge struct {
+	metric  *unboundMetric
+	maxDesc *prometheus.Desc
+	minDesc *prometheus.Desc
+}
+
+type windowSample struct {
+	at    time.Time
+	value float64
+}
+
+type windowSeries struct {
+	gauge   *windowedGauge
+	labels  []string
+	samples []windowSample
+}
+
+// statsPoller queries stats_noreset in the background, more often than
+// Prometheus scrapes, so that short spikes of gauges are not missed.
+// Scrapes are served from the latest poll.
+type statsPoller struct {
+	fetch    statsFetcher
+	interval time.Duration
+	window   time.Duration
+	totals   bool
+	gauges   []*windowedGauge
+
+	mu     sync.Mutex
+	stats  []unboundcontrol.Stat
+	polled time.Time
+	err    error
+	series map[string]*windowSeries
+}
+
+func newStatsPoller(fetch statsFetcher, opts unboundOpts) (*statsPoller, error) {
+	p := &statsPoller{
+		fetch:    fetch,
+		interval: opts.pollInterval,
+		window:   opts.pollWindow,
+		totals:   opts.totals,
+		err:      fmt.Errorf("No statistics polled yet"),
+		series:   map[string]*windowSeries{},
+	}
+	for _, name := range opts.pollGauges {
+		var gauge *unboundMetric
+		for _, metric := range unboundMetrics {
+			if metric.name == name {
+				gauge = metric
+				break
+			}
+		}
+		if gauge == nil || gauge.valueType != prometheus.GaugeValue {
+			return nil, fmt.Errorf("%q is not a gauge exported by unbound_exporter", name)
+		}
+		p.gauges = append(p.gauges, &windowedGauge{
+			metric: gauge,
+			maxDesc: prometheus.NewDesc(
+				prometheus.BuildFQName("unbound", "", name+"_max_over_window"),
+				fmt.Sprintf("Maximum of unbound_%s over the last %s, polled every %s.", name, p.window, p.interval),
+				gauge.labels,
+				nil),
+			minDesc: prometheus.NewDesc(
+				prometheus.BuildFQName("unbound", "", name+"_min_over_window"),
+				fmt.Sprintf("Minimum of unbound_%s over the last %s, polled every %s.", name, p.window, p.interval),
+				gauge.labels,
+				nil),
+		})
+	}
+	return p, nil
+}
+
+func (p *statsPoller) run() {
+	ticker := time.NewTicker(p.interval)
+	defer ticker.Stop()
+	for {
+		p.poll()
+		<-ticker.C
+	}
+}
+
+func (p *statsPoller) poll() {
+	ctx, cancel := context.WithTimeout(context.Background(), p.interval)
+	defer cancel()
+	stats, err := p.fetch(ctx)
+	now := time.Now()
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if err != nil {
+		log.Errorf("Failed to poll statistics: %s", err)
+		p.err = err
+		return
+	}
+	p.stats, p.polled, p.err = stats, now, nil
+
+	for _, stat := range stats {
+		if !exportStat(stat.Name, p.totals) {
+			continue
+		}
+		for _, gauge := range p.gauges {
+			labels, ok := gauge.metric.match(stat.Name)
+			if !ok {
+				continue
+			}
+			key := gauge.metric.name + "\xff" + strings.Join(labels, "\xff")
+			series, ok := p.series[key]
+			if !ok {
+				series = &windowSeries{gauge: gauge, labels: labels}
+				p.series[key] = series
+			}
+			series.samples = append(series.samples, windowSample{at: now, value: stat.Value})
+			break
+		}
+	}
+
+	// Forget samples that fell out of the window, and series that
+	// Unbound no longer reports.
+	for key, series := range p.series {
+		keep := series.samples[:0]
+		for _, sample := range series.samples {
+			if now.Sub(sample.at) <= p.window {
+				keep = append(keep, sample)
+			}
+		}
+		series.samples = keep
+		if len(series.samples) == 0 {
+			delete(p.series, key)
+		}
+	}
+}
+
+// latest returns the statistics of the last successful poll and when they
+// were polled, or the error of the last poll if it failed.
+func (p *statsPoller) latest() ([]unboundcontrol.Stat, time.Time, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.stats, p.polled, p.err
+}
+
+func (p *statsPoller) describe(ch chan<- *prometheus.Desc) {
+	for _, gauge := range p.gauges {
+		ch <- gauge.maxDesc
+		ch <- gauge.minDesc
+	}
+}
+
+func (p *statsPoller) collect(ch chan<- prometheus.Metric) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for _, series := range p.series {
+		lowest, highest := series.samples[0].value, series.samples[0].value
+		for _, sample := range series.samples[1:] {
+			if sample.value < lowest {
+				lowest = sample.value
+			}
+			if sample.value > highest {
+				highest = sample.value
+			}
+		}
+		ch <- prometheus.MustNewConstMetric(
+			series.gauge.maxDesc,
+			prometheus.GaugeValue,
+			highest,
+			series.labels...)
+		ch <- prometheus.MustNewConstMetric(
+			series.gauge.minDesc,
+			prometheus.GaugeValue,
+			lowest,
+			series.labels...)
+	}
+}
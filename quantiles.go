@@ -0,0 +1,124 @@
+// Copyright 2017 Kumina, https://kumina.nl/
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/fuze/unbound_exporter/unboundcontrol"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var (
+	unboundQuantileDesc = prometheus.NewDesc(
+		prometheus.BuildFQName("unbound", "", "response_time_quantile_seconds"),
+		"Estimated query response time quantile over the interval since the previous scrape, interpolated within histogram buckets.",
+		[]string{"quantile"}, nil)
+)
+
+func parseQuantiles(value string) ([]float64, error) {
+	quantiles := []float64{}
+	for _, field := range strings.Split(value, ",") {
+		if field = strings.TrimSpace(field); field == "" {
+			continue
+		}
+		q, err := strconv.ParseFloat(field, 64)
+		if err != nil {
+			return nil, err
+		}
+		if q < 0 || q > 1 {
+			return nil, fmt.Errorf("Quantile %s is not between 0 and 1", field)
+		}
+		quantiles = append(quantiles, q)
+	}
+	return quantiles, nil
+}
+
+// responseTimeQuantiles estimates quantiles from the change of the global
+// histogram between consecutive scrapes, as Unbound's buckets count from
+// server start and quantiles over that would barely move.
+type responseTimeQuantiles struct {
+	quantiles []float64
+	buckets   counterDeltas
+}
+
+func newResponseTimeQuantiles(quantiles []float64) *responseTimeQuantiles {
+	return &responseTimeQuantiles{quantiles: quantiles}
+}
+
+func (q *responseTimeQuantiles) collect(stats []unboundcontrol.Stat, ch chan<- prometheus.Metric) error {
+	histograms, err := parseResponseTimeHistograms(stats)
+	if err != nil {
+		return err
+	}
+	current := histograms["total"].buckets
+	if len(current) == 0 {
+		return nil
+	}
+
+	ends := []float64{}
+	for end := range current {
+		ends = append(ends, end)
+	}
+	sort.Float64s(ends)
+	counts := make([]float64, len(ends))
+	for i, end := range ends {
+		counts[i] = float64(current[end])
+	}
+
+	changes, ok := q.buckets.update(counts)
+	if !ok {
+		return nil
+	}
+	deltas := make([]uint64, len(changes))
+	total := uint64(0)
+	for i, change := range changes {
+		deltas[i] = uint64(change)
+		total += deltas[i]
+	}
+
+	for _, quantile := range q.quantiles {
+		ch <- prometheus.MustNewConstMetric(
+			unboundQuantileDesc,
+			prometheus.GaugeValue,
+			estimateQuantile(quantile, ends, deltas, total),
+			strconv.FormatFloat(quantile, 'g', -1, 64))
+	}
+	return nil
+}
+
+// estimateQuantile interpolates linearly within the bucket holding the
+// quantile. Unbound's buckets are contiguous, so each bucket starts where
+// the previous one ends.
+func estimateQuantile(quantile float64, ends []float64, counts []uint64, total uint64) float64 {
+	if total == 0 {
+		return math.NaN()
+	}
+	rank := quantile * float64(total)
+	seen := 0.0
+	start := 0.0
+	for i, end := range ends {
+		count := float64(counts[i])
+		if count > 0 && seen+count >= rank {
+			return start + (end-start)*(rank-seen)/count
+		}
+		seen += count
+		start = end
+	}
+	return ends[len(ends)-1]
+}
@@ -0,0 +1,68 @@
+// Copyright 2017 Kumina, https://kumina.nl/
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"context"
+	"sort"
+
+	"github.com/fuze/unbound_exporter/unboundcontrol"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var (
+	unboundRatelimitZoneRateDesc = prometheus.NewDesc(
+		prometheus.BuildFQName("unbound", "", "ratelimit_zone_queries_per_second"),
+		"Query rate towards a zone that exceeds its ratelimit, from ratelimit_list.",
+		[]string{"zone"}, nil)
+
+	unboundRatelimitIPRateDesc = prometheus.NewDesc(
+		prometheus.BuildFQName("unbound", "", "ratelimit_ip_queries_per_second"),
+		"Query rate of a client address that exceeds its ip-ratelimit, from ip_ratelimit_list.",
+		[]string{"ip"}, nil)
+)
+
+func collectRatelimits(limits []unboundcontrol.Ratelimit, desc *prometheus.Desc, limit int, ch chan<- prometheus.Metric) {
+	sort.Slice(limits, func(i, j int) bool {
+		if limits[i].Rate != limits[j].Rate {
+			return limits[i].Rate > limits[j].Rate
+		}
+		return limits[i].Name < limits[j].Name
+	})
+	if limit > 0 && len(limits) > limit {
+		limits = limits[:limit]
+	}
+	for _, entry := range limits {
+		ch <- prometheus.MustNewConstMetric(
+			desc,
+			prometheus.GaugeValue,
+			float64(entry.Rate),
+			entry.Name)
+	}
+}
+
+func CollectRatelimitsFromClient(ctx context.Context, client *unboundcontrol.Client, limit int, ch chan<- prometheus.Metric) error {
+	zones, err := client.RatelimitList(ctx, false)
+	if err != nil {
+		return err
+	}
+	ips, err := client.IPRatelimitList(ctx, false)
+	if err != nil {
+		return err
+	}
+
+	collectRatelimits(zones, unboundRatelimitZoneRateDesc, limit, ch)
+	collectRatelimits(ips, unboundRatelimitIPRateDesc, limit, ch)
+	return nil
+}
@@ -0,0 +1,115 @@
+// Copyright 2017 Kumina, https://kumina.nl/
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"context"
+	"sort"
+	"strconv"
+
+	"github.com/fuze/unbound_exporter/unboundcontrol"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var (
+	unboundRequestListOldestDesc = prometheus.NewDesc(
+		prometheus.BuildFQName("unbound", "", "request_list_oldest_age_seconds"),
+		"Time the longest waiting client has been waiting for a pending request. Only covers the first worker thread, which serves the control connection; other threads are not reported.",
+		[]string{"thread"}, nil)
+
+	unboundRequestListAgeDesc = prometheus.NewDesc(
+		prometheus.BuildFQName("unbound", "", "request_list_age_seconds"),
+		"Distribution of the time clients have been waiting for currently pending requests. Only covers the first worker thread, which serves the control connection; other threads are not reported.",
+		[]string{"thread"}, nil)
+
+	unboundRequestListPendingDesc = prometheus.NewDesc(
+		prometheus.BuildFQName("unbound", "", "request_list_pending_age_seconds"),
+		"Time clients have been waiting for the longest pending requests, by query name and type. Only covers the first worker thread, which serves the control connection; other threads are not reported.",
+		[]string{"thread", "qname", "qtype"}, nil)
+
+	unboundRequestListAgeBuckets = []float64{0.01, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30, 60}
+)
+
+func CollectRequestListFromClient(ctx context.Context, client *unboundcontrol.Client, limit int, ch chan<- prometheus.Metric) error {
+	requests, err := client.DumpRequestList(ctx)
+	if err != nil {
+		return err
+	}
+
+	// Internally generated queries have no waiting clients and thus
+	// no age.
+	byThread := map[int][]unboundcontrol.Request{}
+	for _, request := range requests {
+		if request.HasAge {
+			byThread[request.Thread] = append(byThread[request.Thread], request)
+		} else if _, ok := byThread[request.Thread]; !ok {
+			byThread[request.Thread] = []unboundcontrol.Request{}
+		}
+	}
+
+	for thread, pending := range byThread {
+		threadLabel := strconv.Itoa(thread)
+		sort.Slice(pending, func(i, j int) bool {
+			return pending[i].Age > pending[j].Age
+		})
+
+		oldest := 0.0
+		if len(pending) > 0 {
+			oldest = pending[0].Age.Seconds()
+		}
+		ch <- prometheus.MustNewConstMetric(
+			unboundRequestListOldestDesc,
+			prometheus.GaugeValue,
+			oldest,
+			threadLabel)
+
+		sum := 0.0
+		buckets := make(map[float64]uint64)
+		for _, request := range pending {
+			age := request.Age.Seconds()
+			sum += age
+			for _, bound := range unboundRequestListAgeBuckets {
+				if age <= bound {
+					buckets[bound]++
+				}
+			}
+		}
+		ch <- prometheus.MustNewConstHistogram(
+			unboundRequestListAgeDesc,
+			uint64(len(pending)),
+			sum,
+			buckets,
+			threadLabel)
+
+		if limit > 0 && len(pending) > limit {
+			pending = pending[:limit]
+		}
+		seen := map[[2]string]bool{}
+		for _, request := range pending {
+			// Only report the oldest request of a name and type, as
+			// requests may differ in flags only.
+			key := [2]string{request.Name, request.Type}
+			if seen[key] {
+				continue
+			}
+			seen[key] = true
+			ch <- prometheus.MustNewConstMetric(
+				unboundRequestListPendingDesc,
+				prometheus.GaugeValue,
+				request.Age.Seconds(),
+				threadLabel, request.Name, request.Type)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,98 @@
+// Copyright 2017 Kumina, https://kumina.nl/
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"sync"
+
+	"github.com/fuze/unbound_exporter/unboundcontrol"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// resetStartTimeJitter is how far the start time computed from time.now
+// and time.up may move between scrapes without a restart, as both are
+// sampled with limited precision.
+const resetStartTimeJitter = 1.0
+
+var (
+	unboundRestartsDetectedDesc = prometheus.NewDesc(
+		prometheus.BuildFQName("unbound", "", "restarts_detected_total"),
+		"Number of Unbound restarts detected by the exporter, from the start time time.now - time.up moving forward.",
+		nil, nil)
+
+	unboundStatsResetsDetectedDesc = prometheus.NewDesc(
+		prometheus.BuildFQName("unbound", "", "stats_resets_detected_total"),
+		"Number of statistics resets detected by the exporter, such as by unbound-control stats, from the total query count going backwards without a restart.",
+		nil, nil)
+)
+
+// resetDetector compares consecutive statistics to tell Unbound restarts
+// from counter resets, which look the same in the counters themselves.
+type resetDetector struct {
+	mu       sync.Mutex
+	seen     bool
+	start    float64
+	queries  float64
+	restarts uint64
+	resets   uint64
+}
+
+func (d *resetDetector) observe(stats []unboundcontrol.Stat) {
+	now, up, queries := -1.0, -1.0, -1.0
+	for _, stat := range stats {
+		switch stat.Name {
+		case "time.now":
+			now = stat.Value
+		case "time.up":
+			up = stat.Value
+		case "total.num.queries":
+			queries = stat.Value
+		}
+	}
+	if now < 0 || up < 0 || queries < 0 {
+		return
+	}
+	// Comparing uptimes would miss a restart whenever the new uptime has
+	// already passed the previous one.
+	start := now - up
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.seen {
+		if start > d.start+resetStartTimeJitter {
+			d.restarts++
+		} else if queries < d.queries {
+			d.resets++
+		}
+	}
+	d.seen, d.start, d.queries = true, start, queries
+}
+
+func (d *resetDetector) describe(ch chan<- *prometheus.Desc) {
+	ch <- unboundRestartsDetectedDesc
+	ch <- unboundStatsResetsDetectedDesc
+}
+
+func (d *resetDetector) collect(ch chan<- prometheus.Metric) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	ch <- prometheus.MustNewConstMetric(
+		unboundRestartsDetectedDesc,
+		prometheus.CounterValue,
+		float64(d.restarts))
+	ch <- prometheus.MustNewConstMetric(
+		unboundStatsResetsDetectedDesc,
+		prometheus.CounterValue,
+		float64(d.resets))
+}
@@ -0,0 +1,49 @@
+// Copyright 2017 Kumina, https://kumina.nl/
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"testing"
+
+	"github.com/fuze/unbound_exporter/unboundcontrol"
+)
+
+func TestResetDetector(t *testing.T) {
+	var detector resetDetector
+	for _, test := range []struct {
+		name         string
+		now          float64
+		up           float64
+		queries      float64
+		wantRestarts uint64
+		wantResets   uint64
+	}{
+		{name: "first scrape", now: 1000, up: 10, queries: 500},
+		{name: "jitter", now: 1060.4, up: 69.9, queries: 900},
+		// The new uptime is above the previous one.
+		{name: "restart", now: 1120, up: 50, queries: 100, wantRestarts: 1},
+		{name: "stats reset", now: 1180, up: 110, queries: 20, wantRestarts: 1, wantResets: 1},
+		{name: "restart with lower uptime", now: 1240, up: 5, queries: 3, wantRestarts: 2, wantResets: 1},
+	} {
+		detector.observe([]unboundcontrol.Stat{
+			{Name: "time.now", Value: test.now},
+			{Name: "time.up", Value: test.up},
+			{Name: "total.num.queries", Value: test.queries},
+		})
+		if detector.restarts != test.wantRestarts || detector.resets != test.wantResets {
+			t.Errorf("%s: got %d restarts and %d resets, want %d and %d",
+				test.name, detector.restarts, detector.resets, test.wantRestarts, test.wantResets)
+		}
+	}
+}
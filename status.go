@@ -0,0 +1,47 @@
+// Copyright 2017 Kumina, https://kumina.nl/
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"context"
+	"strconv"
+	"strings"
+
+	"github.com/fuze/unbound_exporter/unboundcontrol"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var (
+	unboundBuildInfoDesc = prometheus.NewDesc(
+		prometheus.BuildFQName("unbound", "", "build_info"),
+		"Unbound version, module stack and number of threads, as reported by the status command.",
+		[]string{"version", "modules", "threads"}, nil)
+)
+
+func CollectStatusFromClient(ctx context.Context, client *unboundcontrol.Client, ch chan<- prometheus.Metric) error {
+	status, err := client.Status(ctx)
+	if err != nil {
+		return err
+	}
+
+	ch <- prometheus.MustNewConstMetric(
+		unboundBuildInfoDesc,
+		prometheus.GaugeValue,
+		1.0,
+		status.Version,
+		strings.Join(status.Modules, ","),
+		strconv.Itoa(status.Threads))
+
+	return nil
+}
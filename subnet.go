@@ -0,0 +1,57 @@
+// Copyright 2017 Kumina, https://kumina.nl/
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"github.com/fuze/unbound_exporter/unboundcontrol"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var (
+	unboundSubnetCacheHitRatioDesc = prometheus.NewDesc(
+		prometheus.BuildFQName("unbound", "", "query_subnet_cache_hit_ratio"),
+		"Fraction of queries with EDNS Client Subnet that were answered from the subnet cache, over the interval since the previous scrape.",
+		nil, nil)
+)
+
+// subnetCacheHitRatio computes the ECS cache hit ratio from the change of
+// the subnetcache counters between consecutive scrapes, as a ratio of the
+// counters since server start would barely move.
+type subnetCacheHitRatio struct {
+	counters counterDeltas
+}
+
+func (r *subnetCacheHitRatio) collect(stats []unboundcontrol.Stat, ch chan<- prometheus.Metric) {
+	queries, cacheQueries := -1.0, -1.0
+	for _, stat := range stats {
+		switch stat.Name {
+		case "num.query.subnet":
+			queries = stat.Value
+		case "num.query.subnet_cache":
+			cacheQueries = stat.Value
+		}
+	}
+	if queries < 0 || cacheQueries < 0 {
+		return
+	}
+
+	deltas, ok := r.counters.update([]float64{queries, cacheQueries})
+	// Only emitted when the subnetcache module has seen ECS queries.
+	if ok && deltas[0] > 0 {
+		ch <- prometheus.MustNewConstMetric(
+			unboundSubnetCacheHitRatioDesc,
+			prometheus.GaugeValue,
+			deltas[1]/deltas[0])
+	}
+}
@@ -0,0 +1,56 @@
+// Copyright 2017 Kumina, https://kumina.nl/
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"testing"
+
+	"github.com/fuze/unbound_exporter/unboundcontrol"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+func TestSubnetCacheHitRatio(t *testing.T) {
+	var ratio subnetCacheHitRatio
+	for _, test := range []struct {
+		name         string
+		queries      float64
+		cacheQueries float64
+		want         []float64
+	}{
+		{name: "first scrape", queries: 1000, cacheQueries: 900},
+		{name: "interval", queries: 1100, cacheQueries: 925, want: []float64{0.25}},
+		{name: "no ECS queries", queries: 1100, cacheQueries: 925},
+		{name: "reset", queries: 40, cacheQueries: 30, want: []float64{0.75}},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			stats := []unboundcontrol.Stat{
+				{Name: "num.query.subnet", Value: test.queries},
+				{Name: "num.query.subnet_cache", Value: test.cacheQueries},
+			}
+			metrics := collectMetrics(t, func(ch chan<- prometheus.Metric) error {
+				ratio.collect(stats, ch)
+				return nil
+			})
+			got := metrics[unboundSubnetCacheHitRatioDesc]
+			if len(got) != len(test.want) {
+				t.Fatalf("Got %d samples, want %d", len(got), len(test.want))
+			}
+			for i, want := range test.want {
+				if value := metricValue(got[i]); value != want {
+					t.Errorf("Got %v, want %v", value, want)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,157 @@
+// Copyright 2017 Kumina, https://kumina.nl/
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var (
+	unboundTrustAnchorKeyStateDesc = prometheus.NewDesc(
+		prometheus.BuildFQName("unbound", "", "trust_anchor_key_state"),
+		"RFC 5011 state of a trust anchor key, from the auto-trust-anchor file.",
+		[]string{"zone", "key_tag", "state"}, nil)
+
+	unboundTrustAnchorNextProbeDesc = prometheus.NewDesc(
+		prometheus.BuildFQName("unbound", "", "trust_anchor_next_probe_time_seconds"),
+		"Time at which Unbound will next probe for trust anchor updates, since unix epoch in seconds.",
+		[]string{"zone"}, nil)
+
+	unboundTrustAnchorLastSuccessDesc = prometheus.NewDesc(
+		prometheus.BuildFQName("unbound", "", "trust_anchor_last_success_time_seconds"),
+		"Time of the last successful trust anchor probe, since unix epoch in seconds.",
+		[]string{"zone"}, nil)
+
+	unboundTrustAnchorQueryFailedDesc = prometheus.NewDesc(
+		prometheus.BuildFQName("unbound", "", "trust_anchor_query_failed"),
+		"Number of consecutive failed trust anchor probes.",
+		[]string{"zone"}, nil)
+
+	trustAnchorKeyTagPattern = regexp.MustCompile(`id = (\d+)`)
+	trustAnchorStatePattern  = regexp.MustCompile(`;;state=\d+ \[\s*(\w+)\s*\]`)
+)
+
+type trustAnchorKey struct {
+	tag   string
+	state string
+}
+
+// autoTrustAnchor is the state Unbound keeps in an auto-trust-anchor-file.
+type autoTrustAnchor struct {
+	zone          string
+	lastSuccess   float64
+	nextProbeTime float64
+	queryFailed   float64
+	keys          []trustAnchorKey
+}
+
+// parseAutoTrustAnchor parses an auto-trust-anchor-file, in which Unbound
+// stores its RFC 5011 state as comments, for example
+//
+//	;;id: . 1
+//	;;next_probe_time: 1598917003 ;;Tue Sep  1 00:36:43 2020
+//	.	86400	IN	DNSKEY	257 3 8 AwEAAa... ;{id = 20326 (ksk), size = 2048b} ;;state=2 [  VALID  ] ;;count=0 ;;lastchange=1598875050
+func parseAutoTrustAnchor(file io.Reader) (*autoTrustAnchor, error) {
+	anchor := &autoTrustAnchor{}
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if strings.HasPrefix(line, ";;") {
+			fields := strings.Fields(strings.TrimPrefix(line, ";;"))
+			if len(fields) < 2 {
+				continue
+			}
+			var err error
+			switch fields[0] {
+			case "id:":
+				anchor.zone = fields[1]
+			case "last_success:":
+				anchor.lastSuccess, err = strconv.ParseFloat(fields[1], 64)
+			case "next_probe_time:":
+				anchor.nextProbeTime, err = strconv.ParseFloat(fields[1], 64)
+			case "query_failed:":
+				anchor.queryFailed, err = strconv.ParseFloat(fields[1], 64)
+			}
+			if err != nil {
+				return nil, err
+			}
+			continue
+		}
+
+		if strings.HasPrefix(line, ";") || !strings.Contains(line, "DNSKEY") {
+			continue
+		}
+		tag := trustAnchorKeyTagPattern.FindStringSubmatch(line)
+		state := trustAnchorStatePattern.FindStringSubmatch(line)
+		if tag == nil || state == nil {
+			return nil, fmt.Errorf("%q is not a valid trust anchor key", line)
+		}
+		anchor.keys = append(anchor.keys, trustAnchorKey{
+			tag:   tag[1],
+			state: strings.ToLower(state[1]),
+		})
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if anchor.zone == "" {
+		return nil, fmt.Errorf("No ;;id: line found in trust anchor file")
+	}
+	return anchor, nil
+}
+
+func CollectTrustAnchorFromFile(path string, ch chan<- prometheus.Metric) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	anchor, err := parseAutoTrustAnchor(file)
+	if err != nil {
+		return err
+	}
+
+	for _, key := range anchor.keys {
+		ch <- prometheus.MustNewConstMetric(
+			unboundTrustAnchorKeyStateDesc,
+			prometheus.GaugeValue,
+			1.0,
+			anchor.zone, key.tag, key.state)
+	}
+	ch <- prometheus.MustNewConstMetric(
+		unboundTrustAnchorNextProbeDesc,
+		prometheus.GaugeValue,
+		anchor.nextProbeTime,
+		anchor.zone)
+	ch <- prometheus.MustNewConstMetric(
+		unboundTrustAnchorLastSuccessDesc,
+		prometheus.GaugeValue,
+		anchor.lastSuccess,
+		anchor.zone)
+	ch <- prometheus.MustNewConstMetric(
+		unboundTrustAnchorQueryFailedDesc,
+		prometheus.GaugeValue,
+		anchor.queryFailed,
+		anchor.zone)
+	return nil
+}
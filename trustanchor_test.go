@@ -0,0 +1,76 @@
+// Copyright 2017 Kumina, https://kumina.nl/
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseAutoTrustAnchor(t *testing.T) {
+	file, err := os.Open("testdata/root.key")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+
+	got, err := parseAutoTrustAnchor(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := &autoTrustAnchor{
+		zone:          ".",
+		lastSuccess:   1760831400,
+		nextProbeTime: 1760874143,
+		queryFailed:   2,
+		keys: []trustAnchorKey{
+			{tag: "20326", state: "valid"},
+			{tag: "38696", state: "addpend"},
+			{tag: "19164", state: "revoked"},
+		},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Got %+v, want %+v", got, want)
+	}
+}
+
+func TestParseAutoTrustAnchorErrors(t *testing.T) {
+	for _, test := range []struct {
+		name string
+		file string
+	}{
+		{
+			name: "missing id",
+			file: ";;last_success: 1760831400\n" +
+				".\t86400\tIN\tDNSKEY\t257 3 8 AwEAAa= ;{id = 20326 (ksk), size = 2048b} ;;state=2 [  VALID  ] ;;count=0\n",
+		},
+		{
+			name: "missing state",
+			file: ";;id: . 1\n" +
+				".\t86400\tIN\tDNSKEY\t257 3 8 AwEAAa= ;{id = 20326 (ksk), size = 2048b}\n",
+		},
+		{
+			name: "bad probe time",
+			file: ";;id: . 1\n;;next_probe_time: soon\n",
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			if got, err := parseAutoTrustAnchor(strings.NewReader(test.file)); err == nil {
+				t.Errorf("Expected error, got %+v", got)
+			}
+		})
+	}
+}
@@ -14,23 +14,18 @@
 package main
 
 import (
-	"bufio"
+	"context"
 	"crypto/tls"
-	"crypto/x509"
 	"flag"
-	"fmt"
 	"io"
-	"io/ioutil"
-	"net"
 	"net/http"
 	"net/url"
 	"os"
 	"regexp"
-	"strconv"
 	"strings"
+	"time"
 
-	"sort"
-
+	"github.com/fuze/unbound_exporter/unboundcontrol"
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/client_golang/prometheus/promhttp"
 	"github.com/prometheus/common/log"
@@ -47,6 +42,16 @@ var (
 		"Query response time in seconds.",
 		nil, nil)
 
+	unboundStartTimeDesc = prometheus.NewDesc(
+		prometheus.BuildFQName("unbound", "", "start_time_seconds"),
+		"Start time of the Unbound server since unix epoch in seconds, computed as time.now - time.up.",
+		nil, nil)
+
+	unboundProcessStartTimeDesc = prometheus.NewDesc(
+		prometheus.BuildFQName("unbound", "", "process_start_time_seconds"),
+		"Start time of the Unbound process since unix epoch in seconds, computed as time.now - time.up.",
+		nil, nil)
+
 	unboundMetrics = []*unboundMetric{
 		newUnboundMetric(
 			"answer_rcodes_total",
@@ -55,7 +60,7 @@ var (
 			[]string{"rcode"},
 			"^num\\.answer\\.rcode\\.(\\w+)$"),
 		newUnboundMetric(
-			"answers_bogus",
+			"answers_bogus_total",
 			"Total number of answers that were bogus.",
 			prometheus.CounterValue,
 			nil,
@@ -71,16 +76,40 @@ var (
 			"Total number of queries that were successfully answered using a cache lookup.",
 			prometheus.CounterValue,
 			[]string{"thread"},
-			"^thread(\\d+)\\.num\\.cachehits$"),
+			"^(?:thread(\\d+)|(total))\\.num\\.cachehits$"),
 		newUnboundMetric(
 			"cache_misses_total",
 			"Total number of cache queries that needed recursive processing.",
 			prometheus.CounterValue,
 			[]string{"thread"},
-			"^thread(\\d+)\\.num\\.cachemiss$"),
+			"^(?:thread(\\d+)|(total))\\.num\\.cachemiss$"),
+		newUnboundMetric(
+			"dnscrypt_queries_total",
+			"Total number of queries received by the DNSCrypt module, by kind (crypted, cert, cleartext or malformed).",
+			prometheus.CounterValue,
+			[]string{"thread", "type"},
+			"^(?:thread(\\d+)|(total))\\.num\\.dnscrypt\\.(crypted|cert|cleartext|malformed)$"),
+		newUnboundMetric(
+			"dnscrypt_replayed_queries_total",
+			"Total number of DNSCrypt queries that were replays of earlier queries.",
+			prometheus.CounterValue,
+			nil,
+			"^num\\.query\\.dnscrypt\\.replay$"),
+		newUnboundMetric(
+			"dnscrypt_shared_secret_cache_misses_total",
+			"Total number of DNSCrypt queries whose shared secret was not found in the cache.",
+			prometheus.CounterValue,
+			nil,
+			"^num\\.query\\.dnscrypt\\.shared_secret\\.cachemiss$"),
+		newUnboundMetric(
+			"expired_answers_total",
+			"Total number of replies that were served from expired cache entries (serve-expired).",
+			prometheus.CounterValue,
+			[]string{"thread"},
+			"^(?:thread(\\d+)|(total))\\.num\\.(?:zero_ttl|expired)$"),
 		newUnboundMetric(
 			"memory_caches_bytes",
-			"Memory in bytes in use by caches.",
+			"Memory in bytes in use by caches, including the DNSCrypt shared secret and nonce caches.",
 			prometheus.GaugeValue,
 			[]string{"cache"},
 			"^mem\\.cache\\.(\\w+)$"),
@@ -90,24 +119,48 @@ var (
 			prometheus.GaugeValue,
 			[]string{"module"},
 			"^mem\\.mod\\.(\\w+)$"),
+		newUnboundMetric(
+			"memory_http_bytes",
+			"Memory in bytes in use by DNS-over-HTTPS query and response buffers.",
+			prometheus.GaugeValue,
+			[]string{"buffer"},
+			"^mem\\.http\\.(\\w+)$"),
 		newUnboundMetric(
 			"memory_sbrk_bytes",
 			"Memory in bytes allocated through sbrk.",
 			prometheus.GaugeValue,
 			nil,
 			"^mem\\.total\\.sbrk$"),
+		newUnboundMetric(
+			"memory_stream_wait_bytes",
+			"Memory in bytes in use by the TCP and TLS stream wait buffers.",
+			prometheus.GaugeValue,
+			nil,
+			"^mem\\.streamwait$"),
 		newUnboundMetric(
 			"prefetches_total",
 			"Total number of cache prefetches performed.",
 			prometheus.CounterValue,
 			[]string{"thread"},
-			"^thread(\\d+)\\.num\\.prefetch$"),
+			"^(?:thread(\\d+)|(total))\\.num\\.prefetch$"),
 		newUnboundMetric(
 			"queries_total",
 			"Total number of queries received.",
 			prometheus.CounterValue,
 			[]string{"thread"},
-			"^thread(\\d+)\\.num\\.queries$"),
+			"^(?:thread(\\d+)|(total))\\.num\\.queries$"),
+		newUnboundMetric(
+			"query_aggressive_nsec_total",
+			"Total number of queries answered with a response synthesized from cached NSEC records, by response code.",
+			prometheus.CounterValue,
+			[]string{"rcode"},
+			"^num\\.query\\.aggressive\\.(\\w+)$"),
+		newUnboundMetric(
+			"query_cachedb_total",
+			"Total number of queries that were answered from the cachedb module.",
+			prometheus.CounterValue,
+			nil,
+			"^num\\.query\\.cachedb$"),
 		newUnboundMetric(
 			"query_classes_total",
 			"Total number of queries with a given query class.",
@@ -144,6 +197,36 @@ var (
 			prometheus.CounterValue,
 			nil,
 			"^num\\.query\\.edns\\.present$"),
+		newUnboundMetric(
+			"query_ratelimited_total",
+			"Total number of queries that were not sent to nameservers because of zone ratelimiting.",
+			prometheus.CounterValue,
+			nil,
+			"^num\\.query\\.ratelimited$"),
+		newUnboundMetric(
+			"queries_ip_ratelimited_total",
+			"Total number of queries that were dropped because of client IP ratelimiting.",
+			prometheus.CounterValue,
+			[]string{"thread"},
+			"^(?:thread(\\d+)|(total))\\.num\\.queries_ip_ratelimited$"),
+		newUnboundMetric(
+			"query_subnet_total",
+			"Total number of queries that had an EDNS Client Subnet option and were handled by the subnetcache module.",
+			prometheus.CounterValue,
+			nil,
+			"^num\\.query\\.subnet$"),
+		newUnboundMetric(
+			"query_subnet_cache_total",
+			"Total number of queries with EDNS Client Subnet that were answered from the subnet cache.",
+			prometheus.CounterValue,
+			nil,
+			"^num\\.query\\.subnet_cache$"),
+		newUnboundMetric(
+			"query_https_total",
+			"Total number of queries that were made using HTTPS towards the Unbound server.",
+			prometheus.CounterValue,
+			nil,
+			"^num\\.query\\.https$"),
 		newUnboundMetric(
 			"query_tcp_total",
 			"Total number of queries that were made using TCP towards the Unbound server.",
@@ -156,48 +239,90 @@ var (
 			prometheus.CounterValue,
 			nil,
 			"^num\\.query\\.tls$"),
+		newUnboundMetric(
+			"query_tls_resume_total",
+			"Total number of TLS queries towards the Unbound server that used TLS session resumption.",
+			prometheus.CounterValue,
+			nil,
+			"^num\\.query\\.tls\\.resume$"),
+		newUnboundMetric(
+			"query_tcpout_total",
+			"Total number of queries that the Unbound server made using TCP towards other servers.",
+			prometheus.CounterValue,
+			nil,
+			"^num\\.query\\.tcpout$"),
+		newUnboundMetric(
+			"query_udpout_total",
+			"Total number of queries that the Unbound server made using UDP towards other servers.",
+			prometheus.CounterValue,
+			nil,
+			"^num\\.query\\.udpout$"),
 		newUnboundMetric(
 			"query_types_total",
 			"Total number of queries with a given query type.",
 			prometheus.CounterValue,
 			[]string{"type"},
 			"^num\\.query\\.type\\.([\\w]+)$"),
+		newUnboundMetric(
+			"request_list_avg",
+			"Average size of the request list, including internally generated queries, over the statistics interval.",
+			prometheus.GaugeValue,
+			[]string{"thread"},
+			"^(?:thread(\\d+)|(total))\\.requestlist\\.avg$"),
 		newUnboundMetric(
 			"request_list_current_all",
 			"Current size of the request list, including internally generated queries.",
 			prometheus.GaugeValue,
 			[]string{"thread"},
-			"^thread([0-9]+)\\.requestlist\\.current\\.all$"),
+			"^(?:thread(\\d+)|(total))\\.requestlist\\.current\\.all$"),
 		newUnboundMetric(
 			"request_list_current_user",
 			"Current size of the request list, only counting the requests from client queries.",
 			prometheus.GaugeValue,
 			[]string{"thread"},
-			"^thread([0-9]+)\\.requestlist\\.current\\.user$"),
+			"^(?:thread(\\d+)|(total))\\.requestlist\\.current\\.user$"),
 		newUnboundMetric(
 			"request_list_exceeded_total",
 			"Number of queries that were dropped because the request list was full.",
 			prometheus.CounterValue,
 			[]string{"thread"},
-			"^thread([0-9]+)\\.requestlist\\.exceeded$"),
+			"^(?:thread(\\d+)|(total))\\.requestlist\\.exceeded$"),
+		newUnboundMetric(
+			"request_list_max",
+			"Maximum size of the request list, including internally generated queries, over the statistics interval.",
+			prometheus.GaugeValue,
+			[]string{"thread"},
+			"^(?:thread(\\d+)|(total))\\.requestlist\\.max$"),
 		newUnboundMetric(
 			"request_list_overwritten_total",
 			"Total number of requests in the request list that were overwritten by newer entries.",
 			prometheus.CounterValue,
 			[]string{"thread"},
-			"^thread([0-9]+)\\.requestlist\\.overwritten$"),
+			"^(?:thread(\\d+)|(total))\\.requestlist\\.overwritten$"),
 		newUnboundMetric(
 			"recursive_replies_total",
 			"Total number of replies sent to queries that needed recursive processing.",
 			prometheus.CounterValue,
 			[]string{"thread"},
-			"^thread(\\d+)\\.num\\.recursivereplies$"),
+			"^(?:thread(\\d+)|(total))\\.num\\.recursivereplies$"),
+		newUnboundMetric(
+			"rpz_actions_total",
+			"Total number of queries answered using a response policy zone, by RPZ action.",
+			prometheus.CounterValue,
+			[]string{"action"},
+			"^num\\.rpz\\.action\\.([\\w-]+)$"),
 		newUnboundMetric(
 			"rrset_bogus_total",
 			"Total number of rrsets marked bogus by the validator.",
 			prometheus.CounterValue,
 			nil,
 			"^num\\.rrset\\.bogus$"),
+		newUnboundMetric(
+			"tcp_usage",
+			"Number of TCP buffers in use for incoming connections.",
+			prometheus.GaugeValue,
+			[]string{"thread"},
+			"^(?:thread(\\d+)|(total))\\.tcpusage$"),
 		newUnboundMetric(
 			"time_elapsed_seconds",
 			"Time since last statistics printout in seconds.",
@@ -240,6 +365,18 @@ var (
 			prometheus.GaugeValue,
 			nil,
 			"^total\\.recursion\\.time\\.median$"),
+		newUnboundMetric(
+			"thread_recursion_time_seconds_avg",
+			"Average time it took a thread to answer queries that needed recursive processing (does not include in-cache requests).",
+			prometheus.GaugeValue,
+			[]string{"thread"},
+			"^thread(\\d+)\\.recursion\\.time\\.avg$"),
+		newUnboundMetric(
+			"thread_recursion_time_seconds_median",
+			"The median of the time it took a thread to answer queries that needed recursive processing.",
+			prometheus.GaugeValue,
+			[]string{"thread"},
+			"^thread(\\d+)\\.recursion\\.time\\.median$"),
 		newUnboundMetric(
 			"msg_cache_count",
 			"The Number of Messages cached",
@@ -252,10 +389,44 @@ var (
 			prometheus.GaugeValue,
 			nil,
 			"^rrset\\.cache\\.count$"),
+		newUnboundMetric(
+			"key_cache_count",
+			"The Number of DNSSEC keys cached by the validator",
+			prometheus.GaugeValue,
+			nil,
+			"^key\\.cache\\.count$"),
+		newUnboundMetric(
+			"infra_cache_count",
+			"The Number of upstream servers in the infrastructure cache",
+			prometheus.GaugeValue,
+			nil,
+			"^infra\\.cache\\.count$"),
+		newUnboundMetric(
+			"validation_operations_total",
+			"Total number of DNSSEC validation operations performed by the validator.",
+			prometheus.CounterValue,
+			nil,
+			"^num\\.valops$"),
 	}
 )
 
+// unboundAlwaysTotals are total.* statistics exported without a thread
+// label, as averages and medians cannot be rebuilt from the thread series.
+var unboundAlwaysTotals = map[string]bool{
+	"total.recursion.time.avg":    true,
+	"total.recursion.time.median": true,
+}
+
+// exportStat reports whether a statistic is exported. Unbound sums the
+// per-thread values into total.* keys, which duplicate the thread series,
+// so those are only exported as thread="total" on request.
+func exportStat(name string, totals bool) bool {
+	return totals || !strings.HasPrefix(name, "total.") || unboundAlwaysTotals[name]
+}
+
 type unboundMetric struct {
+	name      string
+	labels    []string
 	desc      *prometheus.Desc
 	valueType prometheus.ValueType
 	pattern   *regexp.Regexp
@@ -263,6 +434,8 @@ type unboundMetric struct {
 
 func newUnboundMetric(name string, description string, valueType prometheus.ValueType, labels []string, pattern string) *unboundMetric {
 	return &unboundMetric{
+		name:   name,
+		labels: labels,
 		desc: prometheus.NewDesc(
 			prometheus.BuildFQName("unbound", "", name),
 			description,
@@ -273,177 +446,297 @@ func newUnboundMetric(name string, description string, valueType prometheus.Valu
 	}
 }
 
-func CollectFromReader(file io.Reader, ch chan<- prometheus.Metric) error {
-	scanner := bufio.NewScanner(file)
-	scanner.Split(bufio.ScanLines)
-	histogramPattern := regexp.MustCompile("^histogram\\.\\d+\\.\\d+\\.to\\.(\\d+\\.\\d+)$")
-
-	histogramCount := uint64(0)
-	histogramAvg := float64(0)
-	histogramBuckets := make(map[float64]uint64)
-
-	for scanner.Scan() {
-		fields := strings.Split(scanner.Text(), "=")
-		if len(fields) != 2 {
-			return fmt.Errorf(
-				"%q is not a valid key-value pair",
-				scanner.Text())
+// match returns the label values captured from a statistic name, or false
+// if the statistic does not belong to the metric. Groups that did not
+// take part in the match, such as the thread number of a total.* key, are
+// skipped.
+func (m *unboundMetric) match(name string) ([]string, bool) {
+	loc := m.pattern.FindStringSubmatchIndex(name)
+	if loc == nil {
+		return nil, false
+	}
+	values := []string{}
+	for i := 2; i < len(loc); i += 2 {
+		if loc[i] >= 0 {
+			values = append(values, name[loc[i]:loc[i+1]])
 		}
+	}
+	return values, true
+}
 
-		for _, metric := range unboundMetrics {
-			if matches := metric.pattern.FindStringSubmatch(fields[0]); matches != nil {
-				value, err := strconv.ParseFloat(fields[1], 64)
+func CollectFromStats(stats []unboundcontrol.Stat, opts unboundOpts, ch chan<- prometheus.Metric) error {
+	timeNow, timeUp := float64(-1), float64(-1)
 
-				if err != nil {
-					return err
-				}
+	for _, stat := range stats {
+		if !exportStat(stat.Name, opts.totals) {
+			continue
+		}
+
+		for _, metric := range unboundMetrics {
+			if labels, ok := metric.match(stat.Name); ok {
 				ch <- prometheus.MustNewConstMetric(
 					metric.desc,
 					metric.valueType,
-					value,
-					matches[1:]...)
+					stat.Value,
+					labels...)
 
 				break
 			}
 		}
 
-		if matches := histogramPattern.FindStringSubmatch(fields[0]); matches != nil {
-			end, err := strconv.ParseFloat(matches[1], 64)
-			if err != nil {
-				return err
-			}
-			value, err := strconv.ParseUint(fields[1], 10, 64)
-
-			if err != nil {
-				return err
-			}
-			histogramBuckets[end] = value
-			histogramCount += value
-		} else if fields[0] == "total.recursion.time.avg" {
-			value, err := strconv.ParseFloat(fields[1], 64)
-			if err != nil {
-				return err
-			}
-			histogramAvg = value
+		if stat.Name == "time.now" {
+			timeNow = stat.Value
+		} else if stat.Name == "time.up" {
+			timeUp = stat.Value
 		}
 	}
 
-	// Convert the metrics to a cumulative Prometheus histogram.
-	// Reconstruct the sum of all samples from the average value
-	// provided by Unbound. Hopefully this does not break
-	// monotonicity.
-	keys := []float64{}
-	for k := range histogramBuckets {
-		keys = append(keys, k)
+	if timeNow >= 0 && timeUp >= 0 {
+		ch <- prometheus.MustNewConstMetric(
+			unboundStartTimeDesc,
+			prometheus.GaugeValue,
+			timeNow-timeUp)
+		ch <- prometheus.MustNewConstMetric(
+			unboundProcessStartTimeDesc,
+			prometheus.GaugeValue,
+			timeNow-timeUp)
 	}
-	sort.Float64s(keys)
-	prev := uint64(0)
-	for _, i := range keys {
-		histogramBuckets[i] += prev
-		prev = histogramBuckets[i]
+
+	histograms, err := parseResponseTimeHistograms(stats)
+	if err != nil {
+		return err
 	}
-	ch <- prometheus.MustNewConstHistogram(
-		unboundHistogram,
-		histogramCount,
-		histogramAvg*float64(histogramCount),
-		histogramBuckets)
+	collectResponseTimeHistograms(histograms, opts, ch)
 
-	return scanner.Err()
+	return nil
 }
 
-func CollectFromFile(path string, ch chan<- prometheus.Metric) error {
+func CollectFromReader(file io.Reader, opts unboundOpts, ch chan<- prometheus.Metric) error {
+	stats, err := unboundcontrol.ParseStats(file)
+	if err != nil {
+		return err
+	}
+	return CollectFromStats(stats, opts, ch)
+}
+
+func CollectFromFile(path string, opts unboundOpts, ch chan<- prometheus.Metric) error {
 	conn, err := os.Open(path)
 	if err != nil {
 		return err
 	}
-	return CollectFromReader(conn, ch)
+	defer conn.Close()
+	return CollectFromReader(conn, opts, ch)
 }
 
-func CollectFromSocket(socketFamily string, host string, tlsConfig *tls.Config, ch chan<- prometheus.Metric) error {
+// unboundOpts holds the settings of the optional collectors.
+type unboundOpts struct {
+	totals             bool
+	histogramPerThread bool
+	histogramSum       string
+	histogramNative    bool
+	quantiles          []float64
+	infra              bool
+	infraLimit         int
+	requestList        bool
+	requestListLimit   int
+	localZones         bool
+	trustAnchorFile    string
+	ratelimit          bool
+	ratelimitLimit     int
+	pollInterval       time.Duration
+	pollWindow         time.Duration
+	pollGauges         []string
+	cacheTTL           time.Duration
+	statsReset         bool
+	stateFile          string
+	timestamps         bool
+	configRefresh      time.Duration
+	timeout            time.Duration
+}
+
+type UnboundExporter struct {
+	client    *unboundcontrol.Client
+	opts      unboundOpts
+	quantiles *responseTimeQuantiles
+	poller    *statsPoller
+	scrapes   *scrapeCache
+	// accumulator is set when counters are read with stats, which
+	// resets them.
+	accumulator *statsAccumulator
+	resets      resetDetector
+	subnet      subnetCacheHitRatio
+	config      *configCache
+}
+
+// statsFetcher queries Unbound's statistics.
+type statsFetcher func(ctx context.Context) ([]unboundcontrol.Stat, error)
+
+func (e *UnboundExporter) fetchStats(ctx context.Context) ([]unboundcontrol.Stat, error) {
 	var (
-		conn net.Conn
-		err  error
+		stats []unboundcontrol.Stat
+		err   error
 	)
-
-	if socketFamily == "unix" {
-		conn, err = net.Dial(socketFamily, host)
+	if e.accumulator == nil {
+		stats, err = e.client.Stats(ctx)
 	} else {
-		conn, err = tls.Dial(socketFamily, host, tlsConfig)
-	}
-	if err != nil {
-		return err
+		stats, err = e.client.StatsReset(ctx)
+		if err == nil {
+			stats = e.accumulator.apply(stats)
+		}
 	}
-	_, err = conn.Write([]byte("UBCT1 stats_noreset\n"))
 	if err != nil {
-		return err
+		return nil, err
 	}
-	return CollectFromReader(conn, ch)
+	e.resets.observe(stats)
+	return stats, nil
 }
 
-type UnboundExporter struct {
-	socketFamily string
-	host         string
-	tlsConfig    tls.Config
-}
-
-func NewUnboundExporter(host string, ca string, cert string, key string) (*UnboundExporter, error) {
+func NewUnboundExporter(host string, ca string, cert string, key string, opts unboundOpts) (*UnboundExporter, error) {
 	u, err := url.Parse(host)
 	if err != nil {
 		return &UnboundExporter{}, err
 	}
 
-	if u.Scheme == "unix" {
-		return &UnboundExporter{
-			socketFamily: u.Scheme,
-			host:         u.Path,
-			tlsConfig:    tls.Config{},
-		}, nil
+	var tlsConfig *tls.Config
+	if u.Scheme != "unix" {
+		tlsConfig, err = unboundcontrol.LoadTLSConfig(ca, cert, key)
+		if err != nil {
+			return &UnboundExporter{}, err
+		}
 	}
 
-	/* Server authentication. */
-	caData, err := ioutil.ReadFile(ca)
+	client, err := unboundcontrol.NewClient(host, tlsConfig)
 	if err != nil {
 		return &UnboundExporter{}, err
 	}
-	roots := x509.NewCertPool()
-	if !roots.AppendCertsFromPEM(caData) {
-		return &UnboundExporter{}, fmt.Errorf("Failed to parse CA")
+	exporter := &UnboundExporter{
+		client:  client,
+		opts:    opts,
+		scrapes: newScrapeCache(opts.cacheTTL),
+		config:  newConfigCache(opts.configRefresh),
 	}
-
-	/* Client authentication. */
-	certData, err := ioutil.ReadFile(cert)
-	if err != nil {
-		return &UnboundExporter{}, err
+	if len(opts.quantiles) > 0 {
+		exporter.quantiles = newResponseTimeQuantiles(opts.quantiles)
 	}
-	keyData, err := ioutil.ReadFile(key)
-	if err != nil {
-		return &UnboundExporter{}, err
+	if opts.statsReset {
+		exporter.accumulator, err = newStatsAccumulator(opts.stateFile)
+		if err != nil {
+			return &UnboundExporter{}, err
+		}
 	}
-	keyPair, err := tls.X509KeyPair(certData, keyData)
-	if err != nil {
-		return &UnboundExporter{}, err
+	if opts.pollInterval > 0 {
+		exporter.poller, err = newStatsPoller(exporter.fetchStats, opts)
+		if err != nil {
+			return &UnboundExporter{}, err
+		}
+		go exporter.poller.run()
 	}
-
-	return &UnboundExporter{
-		socketFamily: u.Scheme,
-		host: u.Host,
-		tlsConfig: tls.Config{
-			Certificates: []tls.Certificate{keyPair},
-			RootCAs:      roots,
-			ServerName:   "unbound",
-		},
-	}, nil
+	return exporter, nil
 }
 
 func (e *UnboundExporter) Describe(ch chan<- *prometheus.Desc) {
 	ch <- unboundUpDesc
+	ch <- unboundStatsAgeDesc
+	ch <- unboundStartTimeDesc
+	ch <- unboundProcessStartTimeDesc
+	ch <- unboundClockSkewDesc
+	e.resets.describe(ch)
+	ch <- unboundSubnetCacheHitRatioDesc
+	if e.quantiles != nil {
+		ch <- unboundQuantileDesc
+	}
+	if e.poller != nil {
+		e.poller.describe(ch)
+	}
+	if e.opts.histogramPerThread {
+		ch <- unboundThreadHistogram
+	} else {
+		ch <- unboundHistogram
+	}
+	ch <- unboundBuildInfoDesc
+	for _, option := range unboundConfigOptions {
+		ch <- option.desc
+	}
+	ch <- unboundForwardZoneInfoDesc
+	ch <- unboundForwardZoneTargetsDesc
+	ch <- unboundStubZoneInfoDesc
+	ch <- unboundStubZoneTargetsDesc
+	ch <- unboundAuthZoneSerialDesc
+	ch <- unboundAuthZoneExpiredDesc
+	if e.opts.infra {
+		ch <- unboundUpstreamRTTDesc
+		ch <- unboundUpstreamTimeoutBackoffDesc
+		ch <- unboundUpstreamLameDesc
+	}
+	if e.opts.requestList {
+		ch <- unboundRequestListOldestDesc
+		ch <- unboundRequestListAgeDesc
+		ch <- unboundRequestListPendingDesc
+	}
+	if e.opts.localZones {
+		ch <- unboundLocalZonesDesc
+		ch <- unboundLocalDataRecordsDesc
+	}
+	if e.opts.ratelimit {
+		ch <- unboundRatelimitZoneRateDesc
+		ch <- unboundRatelimitIPRateDesc
+	}
+	if e.opts.trustAnchorFile != "" {
+		ch <- unboundTrustAnchorKeyStateDesc
+		ch <- unboundTrustAnchorNextProbeDesc
+		ch <- unboundTrustAnchorLastSuccessDesc
+		ch <- unboundTrustAnchorQueryFailedDesc
+	}
 	for _, metric := range unboundMetrics {
 		ch <- metric.desc
 	}
 }
 
 func (e *UnboundExporter) Collect(ch chan<- prometheus.Metric) {
-	err := CollectFromSocket(e.socketFamily, e.host, &e.tlsConfig, ch)
+	metrics, collected := e.scrapes.get(e.collect)
+	for _, metric := range metrics {
+		ch <- metric
+	}
+	ch <- prometheus.MustNewConstMetric(
+		unboundStatsAgeDesc,
+		prometheus.GaugeValue,
+		time.Since(collected).Seconds())
+}
+
+// collect queries Unbound and returns the time the statistics were
+// obtained.
+func (e *UnboundExporter) collect(ch chan<- prometheus.Metric) time.Time {
+	// Concurrent scrapes wait for this collection, so a hung control
+	// connection must not block it forever.
+	ctx := context.Background()
+	if e.opts.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, e.opts.timeout)
+		defer cancel()
+	}
+	var (
+		stats     []unboundcontrol.Stat
+		collected = time.Now()
+		err       error
+	)
+	if e.poller != nil {
+		stats, collected, err = e.poller.latest()
+		if collected.IsZero() {
+			collected = time.Now()
+		}
+	} else {
+		stats, err = e.fetchStats(ctx)
+	}
+	if err == nil {
+		statsCh, done := ch, func() {}
+		if e.opts.timestamps {
+			if ts, ok := statsTime(stats); ok {
+				statsCh, done = withTimestamp(ch, ts)
+			}
+		}
+		err = CollectFromStats(stats, e.opts, statsCh)
+		done()
+	}
 	if err == nil {
 		ch <- prometheus.MustNewConstMetric(
 			unboundUpDesc,
@@ -455,7 +748,58 @@ func (e *UnboundExporter) Collect(ch chan<- prometheus.Metric) {
 			unboundUpDesc,
 			prometheus.GaugeValue,
 			0.0)
+		return collected
+	}
+
+	CollectClockSkew(stats, collected, ch)
+	e.resets.collect(ch)
+	if e.poller != nil {
+		e.poller.collect(ch)
+	}
+	if e.quantiles != nil {
+		if err := e.quantiles.collect(stats, ch); err != nil {
+			log.Errorf("Failed to estimate response time quantiles: %s", err)
+		}
+	}
+	e.subnet.collect(stats, ch)
+	if err := CollectStatusFromClient(ctx, e.client, ch); err != nil {
+		log.Errorf("Failed to query status: %s", err)
+	}
+	if err := e.config.collect(ctx, e.client, ch); err != nil {
+		log.Errorf("Failed to query configuration: %s", err)
+	}
+	if err := CollectZonesFromClient(ctx, e.client, ch); err != nil {
+		log.Errorf("Failed to list forward and stub zones: %s", err)
+	}
+	if err := CollectAuthZonesFromClient(ctx, e.client, ch); err != nil {
+		log.Errorf("Failed to list auth zones: %s", err)
+	}
+	if e.opts.infra {
+		if err := CollectInfraFromClient(ctx, e.client, e.opts.infraLimit, ch); err != nil {
+			log.Errorf("Failed to dump infrastructure cache: %s", err)
+		}
+	}
+	if e.opts.requestList {
+		if err := CollectRequestListFromClient(ctx, e.client, e.opts.requestListLimit, ch); err != nil {
+			log.Errorf("Failed to dump request list: %s", err)
+		}
 	}
+	if e.opts.localZones {
+		if err := CollectLocalZonesFromClient(ctx, e.client, ch); err != nil {
+			log.Errorf("Failed to list local zones: %s", err)
+		}
+	}
+	if e.opts.ratelimit {
+		if err := CollectRatelimitsFromClient(ctx, e.client, e.opts.ratelimitLimit, ch); err != nil {
+			log.Errorf("Failed to list ratelimits: %s", err)
+		}
+	}
+	if e.opts.trustAnchorFile != "" {
+		if err := CollectTrustAnchorFromFile(e.opts.trustAnchorFile, ch); err != nil {
+			log.Errorf("Failed to read trust anchor file: %s", err)
+		}
+	}
+	return collected
 }
 
 func main() {
@@ -466,11 +810,47 @@ func main() {
 		unboundCa     = flag.String("unbound.ca", "/etc/unbound/unbound_server.pem", "Unbound server certificate.")
 		unboundCert   = flag.String("unbound.cert", "/etc/unbound/unbound_control.pem", "Unbound client certificate.")
 		unboundKey    = flag.String("unbound.key", "/etc/unbound/unbound_control.key", "Unbound client key.")
+		opts          = unboundOpts{}
 	)
+	flag.BoolVar(&opts.totals, "unbound.export-totals", false, "Export Unbound's total.* sums as thread=\"total\" series next to the per-thread series. The global recursion time average and median are always exported.")
+	flag.BoolVar(&opts.histogramPerThread, "unbound.histogram-per-thread", false, "Export the response time histogram with a thread label, using per-thread histograms when Unbound provides them.")
+	flag.StringVar(&opts.histogramSum, "unbound.histogram-sum", histogramSumAvg, "How to reconstruct the response time histogram sum: avg (recursion.time.avg times count), midpoint (bucket midpoints) or none (NaN).")
+	flag.BoolVar(&opts.histogramNative, "unbound.histogram-native", false, "Also expose the response time histogram as a Prometheus native histogram, next to the classic buckets.")
+	quantiles := flag.String("unbound.histogram-quantiles", "", "Comma separated response time quantiles to estimate from the histogram change between scrapes, e.g. 0.5,0.9,0.99. Disabled if empty.")
+	flag.DurationVar(&opts.pollInterval, "unbound.poll-interval", 0, "Poll statistics in the background at this interval and serve scrapes from the latest poll. Disabled if 0.")
+	flag.DurationVar(&opts.pollWindow, "unbound.poll-window", time.Minute, "Window over which the minimum and maximum of polled gauges are exported.")
+	pollGauges := flag.String("unbound.poll-gauges", "request_list_current_all,request_list_current_user,memory_caches_bytes", "Comma separated gauges to export _max_over_window and _min_over_window variants of when polling.")
+	flag.DurationVar(&opts.timeout, "unbound.timeout", 10*time.Second, "Timeout for querying Unbound's control socket during a scrape. 0 disables the timeout.")
+	flag.DurationVar(&opts.cacheTTL, "unbound.cache-ttl", 0, "Serve scrapes from the previous collection if it is younger than this. Concurrent scrapes are always coalesced.")
+	flag.BoolVar(&opts.statsReset, "unbound.stats-reset", false, "Read statistics with stats instead of stats_noreset, which resets Unbound's counters, and accumulate them in the exporter.")
+	flag.StringVar(&opts.stateFile, "unbound.state-file", "", "File to persist accumulated counters in across exporter restarts, with -unbound.stats-reset.")
+	flag.BoolVar(&opts.timestamps, "unbound.use-timestamps", false, "Stamp statistics samples with Unbound's time.now instead of leaving the timestamp to Prometheus.")
+	flag.DurationVar(&opts.configRefresh, "unbound.config-refresh-interval", 5*time.Minute, "How often to query the configuration options exported as unbound_config_* gauges. 0 queries them on every scrape.")
+	flag.BoolVar(&opts.infra, "collect.infra", false, "Collect upstream server health from the infrastructure cache (dump_infra).")
+	flag.IntVar(&opts.infraLimit, "collect.infra.limit", 100, "Maximum number of upstream servers to export, worst first. 0 exports all.")
+	flag.BoolVar(&opts.requestList, "collect.requestlist", false, "Collect the ages of pending requests (dump_requestlist).")
+	flag.IntVar(&opts.requestListLimit, "collect.requestlist.limit", 10, "Maximum number of pending requests to export by name, oldest first. 0 exports all.")
+	flag.BoolVar(&opts.localZones, "collect.localzones", false, "Collect local zone and local data counts (list_local_zones, list_local_data).")
+	flag.BoolVar(&opts.ratelimit, "collect.ratelimit", false, "Collect zones and client addresses that exceed their ratelimit (ratelimit_list, ip_ratelimit_list).")
+	flag.IntVar(&opts.ratelimitLimit, "collect.ratelimit.limit", 20, "Maximum number of ratelimited zones and addresses to export each, highest rate first. 0 exports all.")
+	flag.StringVar(&opts.trustAnchorFile, "unbound.trust-anchor-file", "", "Path of Unbound's auto-trust-anchor-file to export RFC 5011 key states from. Disabled if empty.")
 	flag.Parse()
+	if err := validateHistogramSum(opts.histogramSum); err != nil {
+		log.Fatal(err)
+	}
+	parsedQuantiles, err := parseQuantiles(*quantiles)
+	if err != nil {
+		log.Fatal(err)
+	}
+	opts.quantiles = parsedQuantiles
+	for _, gauge := range strings.Split(*pollGauges, ",") {
+		if gauge = strings.TrimSpace(gauge); gauge != "" {
+			opts.pollGauges = append(opts.pollGauges, gauge)
+		}
+	}
 
 	log.Info("Starting unbound_exporter")
-	exporter, err := NewUnboundExporter(*unboundHost, *unboundCa, *unboundCert, *unboundKey)
+	exporter, err := NewUnboundExporter(*unboundHost, *unboundCa, *unboundCert, *unboundKey, opts)
 	if err != nil {
 		panic(err)
 	}
@@ -0,0 +1,208 @@
+// Copyright 2017 Kumina, https://kumina.nl/
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"testing"
+
+	"github.com/fuze/unbound_exporter/unboundcontrol"
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+)
+
+// collectMetrics runs collect and returns the metrics it sends, written
+// out and grouped by descriptor.
+func collectMetrics(t *testing.T, collect func(ch chan<- prometheus.Metric) error) map[*prometheus.Desc][]*dto.Metric {
+	t.Helper()
+	ch := make(chan prometheus.Metric)
+	errc := make(chan error, 1)
+	go func() {
+		errc <- collect(ch)
+		close(ch)
+	}()
+	metrics := map[*prometheus.Desc][]*dto.Metric{}
+	for metric := range ch {
+		pb := &dto.Metric{}
+		if err := metric.Write(pb); err != nil {
+			t.Errorf("Failed to write %s: %s", metric.Desc(), err)
+			continue
+		}
+		metrics[metric.Desc()] = append(metrics[metric.Desc()], pb)
+	}
+	if err := <-errc; err != nil {
+		t.Fatal(err)
+	}
+	return metrics
+}
+
+func metricLabels(metric *dto.Metric) map[string]string {
+	labels := map[string]string{}
+	for _, label := range metric.GetLabel() {
+		labels[label.GetName()] = label.GetValue()
+	}
+	return labels
+}
+
+func metricValue(metric *dto.Metric) float64 {
+	if metric.GetCounter() != nil {
+		return metric.GetCounter().GetValue()
+	}
+	if metric.GetGauge() != nil {
+		return metric.GetGauge().GetValue()
+	}
+	return metric.GetUntyped().GetValue()
+}
+
+// findMetric returns the metric with exactly the given labels, or nil.
+func findMetric(metrics []*dto.Metric, labels map[string]string) *dto.Metric {
+	for _, metric := range metrics {
+		got := metricLabels(metric)
+		if len(got) != len(labels) {
+			continue
+		}
+		match := true
+		for name, value := range labels {
+			if got[name] != value {
+				match = false
+			}
+		}
+		if match {
+			return metric
+		}
+	}
+	return nil
+}
+
+// unboundMetricDesc returns the descriptor of the unbound_<name> family in
+// unboundMetrics.
+func unboundMetricDesc(t *testing.T, name string) *prometheus.Desc {
+	t.Helper()
+	for _, metric := range unboundMetrics {
+		if metric.name == name {
+			return metric.desc
+		}
+	}
+	t.Fatalf("No metric %s", name)
+	return nil
+}
+
+// wantSample is an expected sample of an unbound_<metric> family in
+// unboundMetrics.
+type wantSample struct {
+	metric string
+	labels map[string]string
+	value  float64
+}
+
+func checkSamples(t *testing.T, metrics map[*prometheus.Desc][]*dto.Metric, want []wantSample) {
+	t.Helper()
+	for _, sample := range want {
+		metric := findMetric(metrics[unboundMetricDesc(t, sample.metric)], sample.labels)
+		if metric == nil {
+			t.Errorf("No unbound_%s%v", sample.metric, sample.labels)
+		} else if got := metricValue(metric); got != sample.value {
+			t.Errorf("unbound_%s%v: got %v, want %v", sample.metric, sample.labels, got, sample.value)
+		}
+	}
+}
+
+func TestCollectFromFileDNSCrypt(t *testing.T) {
+	metrics := collectMetrics(t, func(ch chan<- prometheus.Metric) error {
+		return CollectFromFile("testdata/stats_dnscrypt.txt", unboundOpts{}, ch)
+	})
+	checkSamples(t, metrics, []wantSample{
+		{"dnscrypt_queries_total", map[string]string{"thread": "0", "type": "crypted"}, 16007},
+		{"dnscrypt_queries_total", map[string]string{"thread": "0", "type": "cleartext"}, 2011},
+		{"dnscrypt_queries_total", map[string]string{"thread": "1", "type": "cert"}, 401},
+		{"dnscrypt_queries_total", map[string]string{"thread": "1", "type": "malformed"}, 3},
+		{"dnscrypt_replayed_queries_total", map[string]string{}, 7},
+		{"dnscrypt_shared_secret_cache_misses_total", map[string]string{}, 2314},
+		{"memory_caches_bytes", map[string]string{"cache": "dnscrypt_shared_secret"}, 131072},
+		{"memory_caches_bytes", map[string]string{"cache": "dnscrypt_nonce"}, 65536},
+	})
+
+	// Two threads with four kinds each; total.* is not exported by default.
+	if got := len(metrics[unboundMetricDesc(t, "dnscrypt_queries_total")]); got != 8 {
+		t.Errorf("Got %d unbound_dnscrypt_queries_total series, want 8", got)
+	}
+}
+
+func TestCollectFromFileDNSCryptTotals(t *testing.T) {
+	metrics := collectMetrics(t, func(ch chan<- prometheus.Metric) error {
+		return CollectFromFile("testdata/stats_dnscrypt.txt", unboundOpts{totals: true}, ch)
+	})
+	queries := metrics[unboundMetricDesc(t, "dnscrypt_queries_total")]
+	if got := len(queries); got != 12 {
+		t.Errorf("Got %d unbound_dnscrypt_queries_total series, want 12", got)
+	}
+	labels := map[string]string{"thread": "total", "type": "crypted"}
+	if metric := findMetric(queries, labels); metric == nil || metricValue(metric) != 31636 {
+		t.Errorf("unbound_dnscrypt_queries_total%v: got %v, want 31636", labels, metric)
+	}
+}
+
+func TestCollectFromStatsTotals(t *testing.T) {
+	stats := []unboundcontrol.Stat{
+		{Name: "thread0.num.queries", Value: 10},
+		{Name: "thread1.num.queries", Value: 20},
+		{Name: "total.num.queries", Value: 30},
+		{Name: "thread0.requestlist.max", Value: 4},
+		{Name: "thread1.requestlist.max", Value: 7},
+		{Name: "total.requestlist.max", Value: 7},
+		{Name: "thread0.recursion.time.avg", Value: 0.1},
+		{Name: "total.recursion.time.avg", Value: 0.08},
+		{Name: "total.recursion.time.median", Value: 0.03},
+		// Not keys Unbound prints.
+		{Name: "threadtotal.num.queries", Value: 1},
+		{Name: "0.num.queries", Value: 1},
+	}
+
+	for _, test := range []struct {
+		name   string
+		totals bool
+		want   []wantSample
+		series map[string]int
+	}{
+		{
+			name: "default",
+			want: []wantSample{
+				{"recursion_time_seconds_avg", map[string]string{}, 0.08},
+				{"recursion_time_seconds_median", map[string]string{}, 0.03},
+				{"thread_recursion_time_seconds_avg", map[string]string{"thread": "0"}, 0.1},
+			},
+			series: map[string]int{"queries_total": 2, "request_list_max": 2},
+		},
+		{
+			name:   "totals",
+			totals: true,
+			want: []wantSample{
+				{"queries_total", map[string]string{"thread": "total"}, 30},
+				{"recursion_time_seconds_avg", map[string]string{}, 0.08},
+			},
+			series: map[string]int{"queries_total": 3, "request_list_max": 3},
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			metrics := collectMetrics(t, func(ch chan<- prometheus.Metric) error {
+				return CollectFromStats(stats, unboundOpts{totals: test.totals}, ch)
+			})
+			checkSamples(t, metrics, test.want)
+			for name, want := range test.series {
+				if got := len(metrics[unboundMetricDesc(t, name)]); got != want {
+					t.Errorf("Got %d unbound_%s series, want %d", got, name, want)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,182 @@
+// Copyright 2017 Kumina, https://kumina.nl/
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package unboundcontrol implements a client for Unbound's remote control
+// protocol, as spoken by unbound-control(8).
+package unboundcontrol
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/url"
+	"strings"
+)
+
+const protocolHeader = "UBCT1 "
+
+// CommandError is returned when Unbound answers a command with an error.
+type CommandError struct {
+	Command string
+	Message string
+}
+
+func (e *CommandError) Error() string {
+	return fmt.Sprintf("unbound: %s: %s", e.Command, e.Message)
+}
+
+// Client sends commands to a single Unbound control endpoint. Every
+// command is sent over a fresh connection, as Unbound closes the
+// connection once it has written its response.
+type Client struct {
+	network   string
+	address   string
+	tlsConfig *tls.Config
+}
+
+// NewClient returns a client for the control socket at host, which is
+// either unix:///path/to/socket or tcp://host:port. TCP connections are
+// wrapped in TLS unless tlsConfig is nil, which matches Unbound's
+// control-use-cert option.
+func NewClient(host string, tlsConfig *tls.Config) (*Client, error) {
+	u, err := url.Parse(host)
+	if err != nil {
+		return nil, err
+	}
+
+	switch u.Scheme {
+	case "unix":
+		return &Client{
+			network: u.Scheme,
+			address: u.Path,
+		}, nil
+	case "tcp", "tcp4", "tcp6":
+		return &Client{
+			network:   u.Scheme,
+			address:   u.Host,
+			tlsConfig: tlsConfig,
+		}, nil
+	default:
+		return nil, fmt.Errorf("Unsupported control socket scheme %q", u.Scheme)
+	}
+}
+
+// LoadTLSConfig builds the TLS configuration used by unbound-control from
+// the server certificate and the control client key pair.
+func LoadTLSConfig(ca string, cert string, key string) (*tls.Config, error) {
+	/* Server authentication. */
+	caData, err := ioutil.ReadFile(ca)
+	if err != nil {
+		return nil, err
+	}
+	roots := x509.NewCertPool()
+	if !roots.AppendCertsFromPEM(caData) {
+		return nil, fmt.Errorf("Failed to parse CA")
+	}
+
+	/* Client authentication. */
+	certData, err := ioutil.ReadFile(cert)
+	if err != nil {
+		return nil, err
+	}
+	keyData, err := ioutil.ReadFile(key)
+	if err != nil {
+		return nil, err
+	}
+	keyPair, err := tls.X509KeyPair(certData, keyData)
+	if err != nil {
+		return nil, err
+	}
+
+	return &tls.Config{
+		Certificates: []tls.Certificate{keyPair},
+		RootCAs:      roots,
+		ServerName:   "unbound",
+	}, nil
+}
+
+func (c *Client) dial(ctx context.Context) (net.Conn, error) {
+	if c.tlsConfig != nil {
+		dialer := &tls.Dialer{Config: c.tlsConfig}
+		return dialer.DialContext(ctx, c.network, c.address)
+	}
+	var dialer net.Dialer
+	return dialer.DialContext(ctx, c.network, c.address)
+}
+
+// Command sends a control command with its arguments and returns the
+// lines of the response. A response starting with "error" is returned as
+// a *CommandError.
+func (c *Client) Command(ctx context.Context, name string, args ...string) ([]string, error) {
+	command := strings.Join(append([]string{name}, args...), " ")
+	if strings.ContainsAny(command, "\r\n") {
+		return nil, fmt.Errorf("%q is not a valid control command", command)
+	}
+
+	conn, err := c.dial(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	if deadline, ok := ctx.Deadline(); ok {
+		if err := conn.SetDeadline(deadline); err != nil {
+			return nil, err
+		}
+	}
+	// Unblock reads and writes if the context is cancelled mid-command.
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		select {
+		case <-ctx.Done():
+			conn.Close()
+		case <-done:
+		}
+	}()
+
+	if _, err := conn.Write([]byte(protocolHeader + command + "\n")); err != nil {
+		return nil, contextError(ctx, err)
+	}
+
+	lines := []string{}
+	scanner := bufio.NewScanner(conn)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		lines = append(lines, scanner.Text())
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, contextError(ctx, err)
+	}
+
+	if len(lines) > 0 && strings.HasPrefix(lines[0], "error") {
+		return nil, &CommandError{
+			Command: name,
+			Message: strings.TrimSpace(strings.TrimPrefix(lines[0], "error")),
+		}
+	}
+	return lines, nil
+}
+
+// contextError prefers the context's error over the network error it
+// caused, so callers can tell cancellation from connection failures.
+func contextError(ctx context.Context, err error) error {
+	if ctxErr := ctx.Err(); ctxErr != nil {
+		return ctxErr
+	}
+	return err
+}
@@ -0,0 +1,142 @@
+// Copyright 2017 Kumina, https://kumina.nl/
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package unboundcontrol
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// InfraEntry is a single entry of the infrastructure cache, as printed by
+// dump_infra. It describes what Unbound knows about an upstream server
+// address for a given zone.
+type InfraEntry struct {
+	IP   string
+	Zone string
+	// Expired entries only carry their RTO.
+	Expired bool
+	TTL     time.Duration
+	// Ping and Var are the smoothed round trip time and its variance.
+	Ping time.Duration
+	Var  time.Duration
+	// RTT is the round trip time without timeout backoff applied, RTO
+	// the retransmission timeout including backoff.
+	RTT          time.Duration
+	RTO          time.Duration
+	TimeoutA     int
+	TimeoutAAAA  int
+	TimeoutOther int
+	EDNSKnown    bool
+	EDNSVersion  int
+	ProbeDelay   time.Duration
+	LameDNSSEC   bool
+	LameRec      bool
+	LameA        bool
+	LameOther    bool
+}
+
+// Lame reports whether the server is lame for any reason.
+func (e *InfraEntry) Lame() bool {
+	return e.LameDNSSEC || e.LameRec || e.LameA || e.LameOther
+}
+
+// DumpInfra runs dump_infra.
+func (c *Client) DumpInfra(ctx context.Context) ([]InfraEntry, error) {
+	lines, err := c.Command(ctx, "dump_infra")
+	if err != nil {
+		return nil, err
+	}
+	return ParseInfra(lines)
+}
+
+// ParseInfra parses the output of dump_infra. Lines have the form
+//
+//	192.0.2.1 example.com. ttl 600 ping 10 var 5 rtt 50 rto 50 tA 0 tAAAA 0 tother 0 ednsknown 1 edns 0 delay 0 lame dnssec 0 rec 0 A 0 other 0
+//
+// or, for expired entries,
+//
+//	192.0.2.1 example.com. expired rto 120000
+func ParseInfra(lines []string) ([]InfraEntry, error) {
+	entries := []InfraEntry{}
+	for _, line := range lines {
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			continue
+		}
+		if len(fields) < 2 {
+			return nil, fmt.Errorf("%q is not a valid infra cache line", line)
+		}
+		entry := InfraEntry{IP: fields[0], Zone: fields[1]}
+		rest := fields[2:]
+		for len(rest) > 0 {
+			key := rest[0]
+			if key == "expired" {
+				entry.Expired = true
+				rest = rest[1:]
+				continue
+			}
+			if key == "lame" {
+				// "lame" only prefixes the dnssec, rec, A and other flags.
+				rest = rest[1:]
+				continue
+			}
+			if len(rest) < 2 {
+				return nil, fmt.Errorf("%q is not a valid infra cache line", line)
+			}
+			value, err := strconv.Atoi(rest[1])
+			if err != nil {
+				return nil, err
+			}
+			rest = rest[2:]
+
+			switch key {
+			case "ttl":
+				entry.TTL = time.Duration(value) * time.Second
+			case "ping":
+				entry.Ping = time.Duration(value) * time.Millisecond
+			case "var":
+				entry.Var = time.Duration(value) * time.Millisecond
+			case "rtt":
+				entry.RTT = time.Duration(value) * time.Millisecond
+			case "rto":
+				entry.RTO = time.Duration(value) * time.Millisecond
+			case "tA":
+				entry.TimeoutA = value
+			case "tAAAA":
+				entry.TimeoutAAAA = value
+			case "tother":
+				entry.TimeoutOther = value
+			case "ednsknown":
+				entry.EDNSKnown = value != 0
+			case "edns":
+				entry.EDNSVersion = value
+			case "delay":
+				entry.ProbeDelay = time.Duration(value) * time.Second
+			case "dnssec":
+				entry.LameDNSSEC = value != 0
+			case "rec":
+				entry.LameRec = value != 0
+			case "A":
+				entry.LameA = value != 0
+			case "other":
+				entry.LameOther = value != 0
+			}
+		}
+		entries = append(entries, entry)
+	}
+	return entries, nil
+}
@@ -0,0 +1,94 @@
+// Copyright 2017 Kumina, https://kumina.nl/
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package unboundcontrol
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestParseInfra(t *testing.T) {
+	for _, test := range []struct {
+		name    string
+		lines   []string
+		want    []InfraEntry
+		wantErr bool
+	}{
+		{
+			name:  "golden",
+			lines: readGolden(t, "dump_infra"),
+			want: []InfraEntry{
+				{
+					IP:        "192.0.2.1",
+					Zone:      "example.com.",
+					TTL:       598 * time.Second,
+					Ping:      12 * time.Millisecond,
+					Var:       6 * time.Millisecond,
+					RTT:       376 * time.Millisecond,
+					RTO:       376 * time.Millisecond,
+					EDNSKnown: true,
+				},
+				{
+					IP:          "2001:db8::1",
+					Zone:        "example.com.",
+					TTL:         412 * time.Second,
+					Ping:        30 * time.Millisecond,
+					Var:         10 * time.Millisecond,
+					RTT:         376 * time.Millisecond,
+					RTO:         1504 * time.Millisecond,
+					TimeoutA:    1,
+					TimeoutAAAA: 2,
+					EDNSKnown:   true,
+				},
+				{
+					IP:         "198.51.100.7",
+					Zone:       "lame.example.",
+					TTL:        600 * time.Second,
+					Ping:       80 * time.Millisecond,
+					Var:        40 * time.Millisecond,
+					RTT:        376 * time.Millisecond,
+					RTO:        376 * time.Millisecond,
+					ProbeDelay: 2 * time.Second,
+					LameDNSSEC: true,
+				},
+				{
+					IP:      "203.0.113.9",
+					Zone:    "gone.example.",
+					Expired: true,
+					RTO:     120 * time.Second,
+				},
+			},
+		},
+		{name: "missing zone", lines: []string{"192.0.2.1"}, wantErr: true},
+		{name: "missing value", lines: []string{"192.0.2.1 example.com. ttl"}, wantErr: true},
+		{name: "bad value", lines: []string{"192.0.2.1 example.com. ttl 600 ping fast"}, wantErr: true},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			got, err := ParseInfra(test.lines)
+			if test.wantErr {
+				if err == nil {
+					t.Fatalf("expected error, got %+v", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, test.want) {
+				t.Errorf("got %+v, want %+v", got, test.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,46 @@
+// Copyright 2017 Kumina, https://kumina.nl/
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package unboundcontrol
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+// GetOption runs get_option for a single configuration option, such as
+// "msg-cache-size". Options that may be given several times return one
+// value per line.
+func (c *Client) GetOption(ctx context.Context, name string) ([]string, error) {
+	if name == "" || strings.ContainsAny(name, " \t") {
+		return nil, fmt.Errorf("%q is not a valid option name", name)
+	}
+	lines, err := c.Command(ctx, "get_option", name)
+	if err != nil {
+		return nil, err
+	}
+	return ParseOption(lines), nil
+}
+
+// ParseOption parses the output of get_option, which prints one value per
+// line.
+func ParseOption(lines []string) []string {
+	values := []string{}
+	for _, line := range lines {
+		if line = strings.TrimSpace(line); line != "" {
+			values = append(values, line)
+		}
+	}
+	return values
+}
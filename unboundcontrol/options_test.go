@@ -0,0 +1,42 @@
+// Copyright 2017 Kumina, https://kumina.nl/
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package unboundcontrol
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseOption(t *testing.T) {
+	for _, test := range []struct {
+		name  string
+		lines []string
+		want  []string
+	}{
+		{
+			name:  "golden",
+			lines: readGolden(t, "get_option"),
+			want:  []string{"10.0.0.0/8", "172.16.0.0/12", "192.168.0.0/16"},
+		},
+		{name: "single value", lines: []string{"4m"}, want: []string{"4m"}},
+		{name: "blank lines", lines: []string{"", "  yes  ", ""}, want: []string{"yes"}},
+		{name: "empty", lines: []string{}, want: []string{}},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			if got := ParseOption(test.lines); !reflect.DeepEqual(got, test.want) {
+				t.Errorf("got %q, want %q", got, test.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,82 @@
+// Copyright 2017 Kumina, https://kumina.nl/
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package unboundcontrol
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Ratelimit is an entry of ratelimit_list or ip_ratelimit_list. Name is a
+// zone name or a client address respectively.
+type Ratelimit struct {
+	Name string
+	// Rate is the highest query rate seen in the last seconds, Limit
+	// the configured limit for the zone or address.
+	Rate  int
+	Limit int
+}
+
+// RatelimitList runs ratelimit_list. Unless all is set, Unbound only
+// lists zones that are currently over their limit.
+func (c *Client) RatelimitList(ctx context.Context, all bool) ([]Ratelimit, error) {
+	return c.ratelimitList(ctx, "ratelimit_list", all)
+}
+
+// IPRatelimitList runs ip_ratelimit_list. Unless all is set, Unbound only
+// lists addresses that are currently over their limit.
+func (c *Client) IPRatelimitList(ctx context.Context, all bool) ([]Ratelimit, error) {
+	return c.ratelimitList(ctx, "ip_ratelimit_list", all)
+}
+
+func (c *Client) ratelimitList(ctx context.Context, command string, all bool) ([]Ratelimit, error) {
+	args := []string{}
+	if all {
+		args = append(args, "+a")
+	}
+	lines, err := c.Command(ctx, command, args...)
+	if err != nil {
+		return nil, err
+	}
+	return ParseRatelimits(lines)
+}
+
+// ParseRatelimits parses the output of ratelimit_list and
+// ip_ratelimit_list, which prints lines such as
+//
+//	example.com. 150 limit 100
+func ParseRatelimits(lines []string) ([]Ratelimit, error) {
+	limits := []Ratelimit{}
+	for _, line := range lines {
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			continue
+		}
+		if len(fields) != 4 || fields[2] != "limit" {
+			return nil, fmt.Errorf("%q is not a valid ratelimit line", line)
+		}
+		rate, err := strconv.Atoi(fields[1])
+		if err != nil {
+			return nil, err
+		}
+		limit, err := strconv.Atoi(fields[3])
+		if err != nil {
+			return nil, err
+		}
+		limits = append(limits, Ratelimit{Name: fields[0], Rate: rate, Limit: limit})
+	}
+	return limits, nil
+}
@@ -0,0 +1,101 @@
+// Copyright 2017 Kumina, https://kumina.nl/
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package unboundcontrol
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Request is a query Unbound is working on, as printed by
+// dump_requestlist.
+type Request struct {
+	Thread int
+	Num    int
+	Type   string
+	Class  string
+	Name   string
+	// Age is how long clients have been waiting for the answer. It is
+	// only meaningful when HasAge is set, as internally generated
+	// queries have no waiting clients.
+	Age    time.Duration
+	HasAge bool
+	// Module is the module currently handling the query, Status its
+	// description of what it is doing.
+	Module string
+	Status string
+}
+
+// DumpRequestList runs dump_requestlist. Unbound only reports the
+// requests of the thread that serves the control connection.
+func (c *Client) DumpRequestList(ctx context.Context) ([]Request, error) {
+	lines, err := c.Command(ctx, "dump_requestlist")
+	if err != nil {
+		return nil, err
+	}
+	return ParseRequestList(lines)
+}
+
+// ParseRequestList parses the output of dump_requestlist, which looks like
+//
+//	thread #0
+//	#   type cl name    seconds    module status
+//	  0    A IN www.example.com. 0.120000 iterator wait for 192.0.2.1
+func ParseRequestList(lines []string) ([]Request, error) {
+	requests := []Request{}
+	thread := 0
+	for _, line := range lines {
+		if strings.HasPrefix(line, "thread #") {
+			n, err := strconv.Atoi(strings.TrimSpace(strings.TrimPrefix(line, "thread #")))
+			if err != nil {
+				return nil, err
+			}
+			thread = n
+			continue
+		}
+		fields := strings.Fields(line)
+		if len(fields) == 0 || strings.HasPrefix(fields[0], "#") {
+			continue
+		}
+		if len(fields) < 6 {
+			return nil, fmt.Errorf("%q is not a valid request list line", line)
+		}
+		num, err := strconv.Atoi(fields[0])
+		if err != nil {
+			return nil, err
+		}
+		request := Request{
+			Thread: thread,
+			Num:    num,
+			Type:   fields[1],
+			Class:  fields[2],
+			Name:   fields[3],
+			Module: fields[5],
+			Status: strings.Join(fields[6:], " "),
+		}
+		if fields[4] != "-" {
+			seconds, err := strconv.ParseFloat(fields[4], 64)
+			if err != nil {
+				return nil, err
+			}
+			request.Age = time.Duration(seconds * float64(time.Second))
+			request.HasAge = true
+		}
+		requests = append(requests, request)
+	}
+	return requests, nil
+}
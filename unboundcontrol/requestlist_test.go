@@ -0,0 +1,65 @@
+// Copyright 2017 Kumina, https://kumina.nl/
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package unboundcontrol
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestParseRequestList(t *testing.T) {
+	for _, test := range []struct {
+		name    string
+		lines   []string
+		want    []Request
+		wantErr bool
+	}{
+		{
+			name:  "golden",
+			lines: readGolden(t, "dump_requestlist"),
+			want: []Request{
+				{Num: 0, Type: "A", Class: "IN", Name: "www.example.com.", Age: 120 * time.Millisecond, HasAge: true, Module: "iterator", Status: "wait for 192.0.2.1"},
+				{Num: 1, Type: "AAAA", Class: "IN", Name: "ns1.example.net.", Module: "iterator", Status: "wait for 198.51.100.1"},
+				{Num: 2, Type: "MX", Class: "IN", Name: "example.org.", Age: 1500 * time.Millisecond, HasAge: true, Module: "validator", Status: "validator"},
+			},
+		},
+		{
+			name:  "other thread",
+			lines: []string{"thread #3", "  0 A IN example.com. - iterator"},
+			want:  []Request{{Thread: 3, Type: "A", Class: "IN", Name: "example.com.", Module: "iterator", Status: ""}},
+		},
+		{name: "idle", lines: []string{"thread #0", "#   type cl name    seconds    module status"}, want: []Request{}},
+		{name: "bad thread", lines: []string{"thread #one"}, wantErr: true},
+		{name: "missing module", lines: []string{"  0 A IN example.com. -"}, wantErr: true},
+		{name: "bad number", lines: []string{"  x A IN example.com. - iterator"}, wantErr: true},
+		{name: "bad age", lines: []string{"  0 A IN example.com. soon iterator"}, wantErr: true},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			got, err := ParseRequestList(test.lines)
+			if test.wantErr {
+				if err == nil {
+					t.Fatalf("expected error, got %+v", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, test.want) {
+				t.Errorf("got %+v, want %+v", got, test.want)
+			}
+		})
+	}
+}
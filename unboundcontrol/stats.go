@@ -0,0 +1,83 @@
+// Copyright 2017 Kumina, https://kumina.nl/
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package unboundcontrol
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// Stat is a single key=value line of the stats commands, such as
+// thread0.num.queries=1234.
+type Stat struct {
+	Name  string
+	Value float64
+}
+
+// Stats runs stats_noreset, which leaves Unbound's counters untouched.
+func (c *Client) Stats(ctx context.Context) ([]Stat, error) {
+	lines, err := c.Command(ctx, "stats_noreset")
+	if err != nil {
+		return nil, err
+	}
+	return ParseStatsLines(lines)
+}
+
+// StatsReset runs stats, which resets Unbound's counters after reading
+// them.
+func (c *Client) StatsReset(ctx context.Context) ([]Stat, error) {
+	lines, err := c.Command(ctx, "stats")
+	if err != nil {
+		return nil, err
+	}
+	return ParseStatsLines(lines)
+}
+
+// ParseStats parses the output of the stats commands, for example when it
+// was saved to a file with unbound-control stats_noreset.
+func ParseStats(r io.Reader) ([]Stat, error) {
+	lines := []string{}
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		lines = append(lines, scanner.Text())
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return ParseStatsLines(lines)
+}
+
+// ParseStatsLines parses stats output that has already been split into
+// lines.
+func ParseStatsLines(lines []string) ([]Stat, error) {
+	stats := make([]Stat, 0, len(lines))
+	for _, line := range lines {
+		fields := strings.Split(line, "=")
+		if len(fields) != 2 {
+			return nil, fmt.Errorf(
+				"%q is not a valid key-value pair",
+				line)
+		}
+		value, err := strconv.ParseFloat(fields[1], 64)
+		if err != nil {
+			return nil, err
+		}
+		stats = append(stats, Stat{Name: fields[0], Value: value})
+	}
+	return stats, nil
+}
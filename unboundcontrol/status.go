@@ -0,0 +1,94 @@
+// Copyright 2017 Kumina, https://kumina.nl/
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package unboundcontrol
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var statusPIDPattern = regexp.MustCompile(`^unbound \(pid (\d+)\) is running`)
+
+// Status is the output of the status command.
+type Status struct {
+	Version   string
+	Verbosity int
+	Threads   int
+	Modules   []string
+	Uptime    time.Duration
+	Options   []string
+	PID       int
+}
+
+// Status runs status.
+func (c *Client) Status(ctx context.Context) (*Status, error) {
+	lines, err := c.Command(ctx, "status")
+	if err != nil {
+		return nil, err
+	}
+	return ParseStatus(lines)
+}
+
+// ParseStatus parses the output of status. Unknown keys are ignored so
+// that newer Unbound releases do not break parsing.
+func ParseStatus(lines []string) (*Status, error) {
+	status := &Status{}
+	for _, line := range lines {
+		if matches := statusPIDPattern.FindStringSubmatch(line); matches != nil {
+			pid, err := strconv.Atoi(matches[1])
+			if err != nil {
+				return nil, err
+			}
+			status.PID = pid
+			continue
+		}
+
+		fields := strings.SplitN(line, ":", 2)
+		if len(fields) != 2 {
+			return nil, fmt.Errorf("%q is not a valid status line", line)
+		}
+		value := strings.TrimSpace(fields[1])
+
+		var err error
+		switch fields[0] {
+		case "version":
+			status.Version = value
+		case "verbosity":
+			status.Verbosity, err = strconv.Atoi(value)
+		case "threads":
+			status.Threads, err = strconv.Atoi(value)
+		case "modules":
+			// Formatted as: 2 [ validator iterator ]
+			start, end := strings.Index(value, "["), strings.LastIndex(value, "]")
+			if start < 0 || end < start {
+				return nil, fmt.Errorf("%q is not a valid module list", value)
+			}
+			status.Modules = strings.Fields(value[start+1 : end])
+		case "uptime":
+			var seconds int64
+			seconds, err = strconv.ParseInt(strings.TrimSuffix(value, " seconds"), 10, 64)
+			status.Uptime = time.Duration(seconds) * time.Second
+		case "options":
+			status.Options = strings.Fields(value)
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	return status, nil
+}
@@ -0,0 +1,68 @@
+// Copyright 2017 Kumina, https://kumina.nl/
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package unboundcontrol
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestParseStatus(t *testing.T) {
+	for _, test := range []struct {
+		name    string
+		lines   []string
+		want    *Status
+		wantErr bool
+	}{
+		{
+			name:  "golden",
+			lines: readGolden(t, "status"),
+			want: &Status{
+				Version:   "1.17.1",
+				Verbosity: 1,
+				Threads:   4,
+				Modules:   []string{"subnetcache", "validator", "iterator"},
+				Uptime:    86400 * time.Second,
+				Options:   []string{"reuseport", "control(ssl)"},
+				PID:       2112,
+			},
+		},
+		{
+			name:  "unknown keys",
+			lines: []string{"version: 1.19.0", "future: yes"},
+			want:  &Status{Version: "1.19.0"},
+		},
+		{name: "no separator", lines: []string{"version 1.17.1"}, wantErr: true},
+		{name: "bad threads", lines: []string{"threads: four"}, wantErr: true},
+		{name: "bad modules", lines: []string{"modules: 2 validator iterator"}, wantErr: true},
+		{name: "bad uptime", lines: []string{"uptime: a day"}, wantErr: true},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			got, err := ParseStatus(test.lines)
+			if test.wantErr {
+				if err == nil {
+					t.Fatalf("expected error, got %+v", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, test.want) {
+				t.Errorf("got %+v, want %+v", got, test.want)
+			}
+		})
+	}
+}
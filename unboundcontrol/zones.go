@@ -0,0 +1,217 @@
+// Copyright 2017 Kumina, https://kumina.nl/
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package unboundcontrol
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Zone is a forward or stub zone, as printed by list_forwards and
+// list_stubs.
+type Zone struct {
+	Name  string
+	Class string
+	// Type is either "forward" or "stub".
+	Type string
+	// Flags holds modifiers printed after the type: "+i" for zones that
+	// are insecure (not DNSSEC validated), and "prime" or "noprime" for
+	// stubs.
+	Flags []string
+	// Targets holds the nameserver names and addresses of the zone.
+	Targets []string
+}
+
+// ListForwards runs list_forwards.
+func (c *Client) ListForwards(ctx context.Context) ([]Zone, error) {
+	lines, err := c.Command(ctx, "list_forwards")
+	if err != nil {
+		return nil, err
+	}
+	return ParseZones(lines)
+}
+
+// ListStubs runs list_stubs.
+func (c *Client) ListStubs(ctx context.Context) ([]Zone, error) {
+	lines, err := c.Command(ctx, "list_stubs")
+	if err != nil {
+		return nil, err
+	}
+	return ParseZones(lines)
+}
+
+// ParseZones parses the output of list_forwards and list_stubs.
+func ParseZones(lines []string) ([]Zone, error) {
+	zones := []Zone{}
+	for _, line := range lines {
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			continue
+		}
+		if len(fields) < 3 {
+			return nil, fmt.Errorf("%q is not a valid zone line", line)
+		}
+		zone := Zone{
+			Name:    fields[0],
+			Class:   fields[1],
+			Type:    fields[2],
+			Flags:   []string{},
+			Targets: []string{},
+		}
+		rest := fields[3:]
+		for len(rest) > 0 && isZoneFlag(rest[0]) {
+			zone.Flags = append(zone.Flags, rest[0])
+			rest = rest[1:]
+		}
+		zone.Targets = append(zone.Targets, rest...)
+		zones = append(zones, zone)
+	}
+	return zones, nil
+}
+
+func isZoneFlag(field string) bool {
+	return strings.HasPrefix(field, "+") || field == "prime" || field == "noprime"
+}
+
+// LocalZone is a local zone, as printed by list_local_zones.
+type LocalZone struct {
+	Name string
+	// Type is the local-zone type, such as "static" or "transparent".
+	Type string
+}
+
+// ListLocalZones runs list_local_zones.
+func (c *Client) ListLocalZones(ctx context.Context) ([]LocalZone, error) {
+	lines, err := c.Command(ctx, "list_local_zones")
+	if err != nil {
+		return nil, err
+	}
+	return ParseLocalZones(lines)
+}
+
+// ParseLocalZones parses the output of list_local_zones.
+func ParseLocalZones(lines []string) ([]LocalZone, error) {
+	zones := []LocalZone{}
+	for _, line := range lines {
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			continue
+		}
+		if len(fields) != 2 {
+			return nil, fmt.Errorf("%q is not a valid local zone line", line)
+		}
+		zones = append(zones, LocalZone{Name: fields[0], Type: fields[1]})
+	}
+	return zones, nil
+}
+
+// LocalData is a single local-data record, as printed by list_local_data.
+type LocalData struct {
+	Name  string
+	TTL   uint32
+	Class string
+	Type  string
+	Data  string
+}
+
+// ListLocalData runs list_local_data.
+func (c *Client) ListLocalData(ctx context.Context) ([]LocalData, error) {
+	lines, err := c.Command(ctx, "list_local_data")
+	if err != nil {
+		return nil, err
+	}
+	return ParseLocalData(lines)
+}
+
+// ParseLocalData parses the output of list_local_data, which prints one
+// record per line in zone file format.
+func ParseLocalData(lines []string) ([]LocalData, error) {
+	records := []LocalData{}
+	for _, line := range lines {
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			continue
+		}
+		if len(fields) < 4 {
+			return nil, fmt.Errorf("%q is not a valid local data line", line)
+		}
+		ttl, err := strconv.ParseUint(fields[1], 10, 32)
+		if err != nil {
+			return nil, err
+		}
+		records = append(records, LocalData{
+			Name:  fields[0],
+			TTL:   uint32(ttl),
+			Class: fields[2],
+			Type:  fields[3],
+			Data:  strings.Join(fields[4:], " "),
+		})
+	}
+	return records, nil
+}
+
+// AuthZone is an authority zone, as printed by list_auth_zones.
+type AuthZone struct {
+	Name string
+	// Serial is only meaningful when HasSerial is set.
+	Serial    uint32
+	HasSerial bool
+	Expired   bool
+}
+
+// ListAuthZones runs list_auth_zones.
+func (c *Client) ListAuthZones(ctx context.Context) ([]AuthZone, error) {
+	lines, err := c.Command(ctx, "list_auth_zones")
+	if err != nil {
+		return nil, err
+	}
+	return ParseAuthZones(lines)
+}
+
+// ParseAuthZones parses the output of list_auth_zones, which prints the
+// zone name followed by "serial N", "no serial" or "expired".
+func ParseAuthZones(lines []string) ([]AuthZone, error) {
+	zones := []AuthZone{}
+	for _, line := range lines {
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			continue
+		}
+		zone := AuthZone{Name: fields[0]}
+		for i := 1; i < len(fields); i++ {
+			switch fields[i] {
+			case "expired":
+				zone.Expired = true
+			case "no":
+				// "no serial"
+				i++
+			case "serial":
+				if i+1 >= len(fields) {
+					return nil, fmt.Errorf("%q is not a valid auth zone line", line)
+				}
+				i++
+				serial, err := strconv.ParseUint(fields[i], 10, 32)
+				if err != nil {
+					return nil, err
+				}
+				zone.Serial = uint32(serial)
+				zone.HasSerial = true
+			}
+		}
+		zones = append(zones, zone)
+	}
+	return zones, nil
+}
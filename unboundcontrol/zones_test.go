@@ -0,0 +1,183 @@
+// Copyright 2017 Kumina, https://kumina.nl/
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package unboundcontrol
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseZones(t *testing.T) {
+	for _, test := range []struct {
+		name    string
+		lines   []string
+		want    []Zone
+		wantErr bool
+	}{
+		{
+			name:  "list_forwards",
+			lines: readGolden(t, "list_forwards"),
+			want: []Zone{
+				{Name: ".", Class: "IN", Type: "forward", Flags: []string{}, Targets: []string{"9.9.9.9", "149.112.112.112"}},
+				{Name: "corp.example.", Class: "IN", Type: "forward", Flags: []string{"+i"}, Targets: []string{"10.0.0.53"}},
+				{Name: "example.net.", Class: "IN", Type: "forward", Flags: []string{}, Targets: []string{"ns1.example.net.", "192.0.2.53"}},
+			},
+		},
+		{
+			name:  "list_stubs",
+			lines: readGolden(t, "list_stubs"),
+			want: []Zone{
+				{Name: "internal.example.", Class: "IN", Type: "stub", Flags: []string{"noprime"}, Targets: []string{"10.1.0.1", "10.1.0.2"}},
+				{Name: "lan.", Class: "IN", Type: "stub", Flags: []string{"noprime", "+i"}, Targets: []string{"192.168.1.1"}},
+				{Name: "root-servers.net.", Class: "IN", Type: "stub", Flags: []string{"prime"}, Targets: []string{"192.0.2.1"}},
+			},
+		},
+		{
+			name:  "no targets",
+			lines: []string{"example.com. IN forward"},
+			want:  []Zone{{Name: "example.com.", Class: "IN", Type: "forward", Flags: []string{}, Targets: []string{}}},
+		},
+		{name: "empty", lines: []string{""}, want: []Zone{}},
+		{name: "missing type", lines: []string{"example.com. IN"}, wantErr: true},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			got, err := ParseZones(test.lines)
+			if test.wantErr {
+				if err == nil {
+					t.Fatalf("expected error, got %+v", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, test.want) {
+				t.Errorf("got %+v, want %+v", got, test.want)
+			}
+		})
+	}
+}
+
+func TestParseLocalZones(t *testing.T) {
+	for _, test := range []struct {
+		name    string
+		lines   []string
+		want    []LocalZone
+		wantErr bool
+	}{
+		{
+			name:  "golden",
+			lines: readGolden(t, "list_local_zones"),
+			want: []LocalZone{
+				{Name: "127.in-addr.arpa.", Type: "static"},
+				{Name: "example.org.", Type: "transparent"},
+				{Name: "localhost.", Type: "redirect"},
+			},
+		},
+		{name: "missing type", lines: []string{"example.org."}, wantErr: true},
+		{name: "extra field", lines: []string{"example.org. static extra"}, wantErr: true},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			got, err := ParseLocalZones(test.lines)
+			if test.wantErr {
+				if err == nil {
+					t.Fatalf("expected error, got %+v", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, test.want) {
+				t.Errorf("got %+v, want %+v", got, test.want)
+			}
+		})
+	}
+}
+
+func TestParseLocalData(t *testing.T) {
+	for _, test := range []struct {
+		name    string
+		lines   []string
+		want    []LocalData
+		wantErr bool
+	}{
+		{
+			name:  "golden",
+			lines: readGolden(t, "list_local_data"),
+			want: []LocalData{
+				{Name: "localhost.", TTL: 10800, Class: "IN", Type: "NS", Data: "localhost."},
+				{Name: "localhost.", TTL: 10800, Class: "IN", Type: "SOA", Data: "localhost. nobody.invalid. 1 3600 1200 604800 10800"},
+				{Name: "localhost.", TTL: 10800, Class: "IN", Type: "A", Data: "127.0.0.1"},
+				{Name: "www.example.org.", TTL: 3600, Class: "IN", Type: "A", Data: "192.0.2.10"},
+			},
+		},
+		{name: "missing type", lines: []string{"www.example.org. 3600 IN"}, wantErr: true},
+		{name: "bad ttl", lines: []string{"www.example.org. 1h IN A 192.0.2.10"}, wantErr: true},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			got, err := ParseLocalData(test.lines)
+			if test.wantErr {
+				if err == nil {
+					t.Fatalf("expected error, got %+v", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, test.want) {
+				t.Errorf("got %+v, want %+v", got, test.want)
+			}
+		})
+	}
+}
+
+func TestParseAuthZones(t *testing.T) {
+	for _, test := range []struct {
+		name    string
+		lines   []string
+		want    []AuthZone
+		wantErr bool
+	}{
+		{
+			name:  "golden",
+			lines: readGolden(t, "list_auth_zones"),
+			want: []AuthZone{
+				{Name: ".", Serial: 2024101900, HasSerial: true},
+				{Name: "broken.example.", Expired: true},
+				{Name: "example.org.", Serial: 2024010101, HasSerial: true},
+				{Name: "new.example."},
+			},
+		},
+		{name: "missing serial", lines: []string{"example.org.\tserial"}, wantErr: true},
+		{name: "bad serial", lines: []string{"example.org.\tserial none"}, wantErr: true},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			got, err := ParseAuthZones(test.lines)
+			if test.wantErr {
+				if err == nil {
+					t.Fatalf("expected error, got %+v", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, test.want) {
+				t.Errorf("got %+v, want %+v", got, test.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,75 @@
+// Copyright 2017 Kumina, https://kumina.nl/
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"context"
+	"strings"
+
+	"github.com/fuze/unbound_exporter/unboundcontrol"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var (
+	unboundForwardZoneInfoDesc = prometheus.NewDesc(
+		prometheus.BuildFQName("unbound", "", "forward_zone_info"),
+		"Forward zones configured in Unbound, with their comma separated targets.",
+		[]string{"zone", "targets"}, nil)
+
+	unboundForwardZoneTargetsDesc = prometheus.NewDesc(
+		prometheus.BuildFQName("unbound", "", "forward_zone_targets"),
+		"Number of targets configured for a forward zone.",
+		[]string{"zone"}, nil)
+
+	unboundStubZoneInfoDesc = prometheus.NewDesc(
+		prometheus.BuildFQName("unbound", "", "stub_zone_info"),
+		"Stub zones configured in Unbound, with their comma separated targets.",
+		[]string{"zone", "targets"}, nil)
+
+	unboundStubZoneTargetsDesc = prometheus.NewDesc(
+		prometheus.BuildFQName("unbound", "", "stub_zone_targets"),
+		"Number of targets configured for a stub zone.",
+		[]string{"zone"}, nil)
+)
+
+func collectZones(zones []unboundcontrol.Zone, infoDesc *prometheus.Desc, targetsDesc *prometheus.Desc, ch chan<- prometheus.Metric) {
+	for _, zone := range zones {
+		ch <- prometheus.MustNewConstMetric(
+			infoDesc,
+			prometheus.GaugeValue,
+			1.0,
+			zone.Name,
+			strings.Join(zone.Targets, ","))
+		ch <- prometheus.MustNewConstMetric(
+			targetsDesc,
+			prometheus.GaugeValue,
+			float64(len(zone.Targets)),
+			zone.Name)
+	}
+}
+
+func CollectZonesFromClient(ctx context.Context, client *unboundcontrol.Client, ch chan<- prometheus.Metric) error {
+	forwards, err := client.ListForwards(ctx)
+	if err != nil {
+		return err
+	}
+	stubs, err := client.ListStubs(ctx)
+	if err != nil {
+		return err
+	}
+
+	collectZones(forwards, unboundForwardZoneInfoDesc, unboundForwardZoneTargetsDesc, ch)
+	collectZones(stubs, unboundStubZoneInfoDesc, unboundStubZoneTargetsDesc, ch)
+	return nil
+}